@@ -1,28 +1,54 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 	"github.com/sosshik/grpc-user-managment/internal/api"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
 	"github.com/sosshik/grpc-user-managment/internal/database"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events"
+	"github.com/sosshik/grpc-user-managment/internal/gateway"
+	"github.com/sosshik/grpc-user-managment/internal/jobs"
+	"github.com/sosshik/grpc-user-managment/internal/notify"
+	"github.com/sosshik/grpc-user-managment/internal/requestid"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	"github.com/sosshik/grpc-user-managment/internal/webhooks"
 	"github.com/sosshik/grpc-user-managment/pkg/config"
 	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-var cfg *config.Config
-
-func init() {
+// loadConfig reads the config from the environment and the .env file and
+// sets up logging.
+func loadConfig() (*config.Config, error) {
 	err := godotenv.Load()
 	if err != nil {
 		log.Warn("No .env file")
 	}
 
-	cfg = config.GetConfig()
+	cfg, err := config.New()
+	if err != nil {
+		return nil, err
+	}
 
 	level, err := log.ParseLevel(cfg.LogLevel)
 	if err != nil {
@@ -36,24 +62,288 @@ func init() {
 		FullTimestamp: true,
 	})
 	fmt.Printf("config initialized\n")
+	return cfg, nil
 }
+
 func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.NewDatabase(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
 
-	db, err := database.NewDatabase(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if cfg.MigrateOnStart {
+		m, err := database.NewMigrator(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := m.Up(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tokens, err := newTokenManager(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notifier, err := notify.New(cfg.Notifier, cfg.NotifierFile)
 	if err != nil {
-		log.Warn(err)
+		log.Fatal(err)
+	}
+
+	publisher, err := events.New(cfg.EventPublisher, cfg.EventPublisherFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	broker := events.NewBroker()
+	relay := &events.Relay{DB: db, Publisher: events.Multi{publisher, broker, &webhooks.Dispatcher{DB: db}}}
+	sender := &webhooks.Sender{
+		DB:          db,
+		Client:      &http.Client{Timeout: cfg.WebhookTimeout},
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		RetryBase:   cfg.WebhookRetryBase,
 	}
-	defer db.DB.Close()
 
-	s := grpc.NewServer()
-	srv := &api.ServerAPI{DB: db}
-	proto.RegisterUserServiceServer(s, srv)
+	if cfg.AdminEmail != "" {
+		grantAdmin(ctx, db, cfg.AdminEmail)
+	}
+
+	services, err := auth.ParseServiceRoles(cfg.ServiceRoles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authenticator := &auth.Authenticator{Tokens: tokens, DB: db, Services: services}
+	authorizer := &auth.Authorizer{}
+
+	interceptors := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(requestid.UnaryInterceptor, gateway.UnaryInterceptor, authenticator.UnaryInterceptor, authorizer.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(requestid.StreamInterceptor, gateway.StreamInterceptor, authenticator.StreamInterceptor, authorizer.StreamInterceptor),
+	}
+	srv := &api.ServerAPI{
+		DB:                   db,
+		Tokens:               tokens,
+		Notifier:             notifier,
+		ResetTokenTTL:        cfg.PasswordResetTTL,
+		VerificationTokenTTL: cfg.EmailVerificationTTL,
+		Events:               broker,
+		WatchPollInterval:    cfg.OutboxRelayInterval,
+		Stopping:             ctx.Done(),
+	}
+	s, gw, err := newServers(cfg, srv, interceptors...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthSrv)
+	if cfg.GRPCReflection {
+		reflection.Register(s)
+	}
+	if cfg.ConnCheck {
+		monitor := &database.Monitor{
+			DB:       db.DB,
+			Interval: cfg.HealthCheckInterval,
+			OnChange: func(healthy bool) { setServingStatus(healthSrv, healthy) },
+		}
+		go monitor.Run(ctx)
+	}
+	go jobs.Run(ctx, "unverified users cleanup", cfg.UnverifiedCleanupInterval, func(ctx context.Context) error {
+		n, err := db.DeleteUnverifiedUsers(ctx, time.Now().Add(-cfg.UnverifiedRetention))
+		if n > 0 {
+			log.Infof("Deleted %d users that never verified their email", n)
+		}
+		return err
+	})
+	go jobs.Run(ctx, "suspensions check", cfg.SuspensionCheckInterval, func(ctx context.Context) error {
+		n, err := db.LiftSuspensions(ctx, time.Now())
+		if n > 0 {
+			log.Infof("Activated %d users whose suspension ended", n)
+		}
+		return err
+	})
+	go jobs.Run(ctx, "deleted users purge", cfg.PurgeInterval, func(ctx context.Context) error {
+		n, err := db.PurgeDeletedUsers(ctx, time.Now().Add(-cfg.DeletedRetention))
+		if n > 0 {
+			log.Infof("Purged %d deleted users", n)
+		}
+		return err
+	})
+	go jobs.Run(ctx, "outbox relay", cfg.OutboxRelayInterval, relay.Run)
+	go jobs.Run(ctx, "webhook deliveries", cfg.WebhookSendInterval, sender.Run)
+	go jobs.Run(ctx, "outbox cleanup", cfg.PurgeInterval, func(ctx context.Context) error {
+		n, err := db.PurgeUserEvents(ctx, time.Now().Add(-cfg.OutboxRetention))
+		if n > 0 {
+			log.Infof("Removed %d published user events", n)
+		}
+		return err
+	})
+
+	gl, err := net.Listen("tcp", ":"+cfg.HTTPPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		log.Infof("Serving REST gateway on %s", gl.Addr())
+		if err := gw.Serve(gl); err != nil {
+			log.Warnf("gateway: %s", err)
+		}
+	}()
 
-	l, err := net.Listen("tcp", ":8080")
+	l, err := net.Listen("tcp", ":"+cfg.Port)
 	if err != nil {
-		log.Warn(err)
+		log.Fatal(err)
 	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdown(s, gw, healthSrv, cfg.ShutdownTimeout)
+	}()
+
+	log.Infof("Serving gRPC on %s", l.Addr())
 	if err := s.Serve(l); err != nil {
-		log.Warn(err)
+		log.Fatal(err)
+	}
+	<-stopped
+	srv.Wait()
+}
+
+// setServingStatus reports the service as serving while the database
+// answers.
+func setServingStatus(hs *health.Server, healthy bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if healthy {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	hs.SetServingStatus("", status)
+	hs.SetServingStatus(proto.UserService_ServiceDesc.ServiceName, status)
+}
+
+// shutdown stops taking new calls and waits up to timeout for the running
+// ones to finish before closing their connections.
+func shutdown(s *grpc.Server, gw *gateway.Server, hs *health.Server, timeout time.Duration) {
+	log.Infof("Shutting down, waiting up to %s for running calls", timeout)
+	hs.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := gw.Shutdown(ctx); err != nil {
+		log.Warnf("gateway did not stop in time: %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warn("Calls still running after the shutdown timeout, closing them")
+		s.Stop()
+	}
+}
+
+// grantAdmin gives the admin role to the user with the email, so that a new
+// deployment has someone able to grant roles.
+func grantAdmin(ctx context.Context, db *database.Database, email string) {
+	creds, err := db.GetCredentials(ctx, email, "")
+	if err != nil {
+		log.Warnf("unable to find admin %s: %s", email, err)
+		return
+	}
+	ctx = auth.NewContext(ctx, &auth.Principal{Service: domain.SystemActor})
+	if err := db.GrantRole(ctx, creds.Oid, domain.RoleAdmin); err != nil {
+		log.Warnf("unable to grant admin role to %s: %s", email, err)
+		return
+	}
+	log.Infof("User %s has the admin role", email)
+}
+
+// newServers returns the public gRPC server, using TLS if it is configured,
+// and the REST/JSON gateway. Both serve service behind the interceptors.
+func newServers(cfg *config.Config, service proto.UserServiceServer, interceptors ...grpc.ServerOption) (*grpc.Server, *gateway.Server, error) {
+	opts := slices.Clip(interceptors)
+	if cfg.TLSCertFile != "" {
+		creds, err := serverCredentials(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(opts...)
+	proto.RegisterUserServiceServer(s, service)
+
+	gw, err := gateway.NewServer(context.Background(), service, interceptors...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, gw, nil
+}
+
+// serverCredentials loads the TLS certificate of the server. If a client CA
+// is configured, services may authenticate with client certificates issued
+// by it.
+func serverCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS certificate: %w", err)
 	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func newTokenManager(cfg *config.Config) (*token.Manager, error) {
+	var key ed25519.PrivateKey
+	var err error
+	if cfg.TokenSigningKey == "" {
+		log.Warn("TOKEN_SIGNING_KEY is not set, using a random key: tokens won't survive a restart")
+		key, err = token.GenerateSigningKey()
+	} else {
+		key, err = token.ParseSigningKey(cfg.TokenSigningKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return token.NewManager(token.Config{
+		Issuer:     cfg.TokenIssuer,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+		SigningKey: key,
+	}), nil
 }
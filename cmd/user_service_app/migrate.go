@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/database"
+)
+
+const migrateUsage = "usage: user_service_app migrate up|down|status|redo"
+
+// runMigrate runs the migrate subcommand named by args.
+func runMigrate(ctx context.Context, db *database.Database, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	m, err := database.NewMigrator(db.DB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "redo":
+		return m.Redo(ctx)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q, %s", args[0], migrateUsage)
+}
+
+func printStatus(statuses []database.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tMIGRATION\tAPPLIED AT")
+	for _, st := range statuses {
+		applied := "pending"
+		if !st.AppliedAt.IsZero() {
+			applied = st.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	w.Flush()
+}
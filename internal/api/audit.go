@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/api/convert"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+)
+
+func (s *ServerAPI) ListAuditEvents(ctx context.Context, req *proto.ListAuditEventsRequest) (*proto.ListAuditEventsResponse, error) {
+	query, err := listAuditEventsQuery(req)
+	if err != nil {
+		return &proto.ListAuditEventsResponse{}, toStatus("ListAuditEvents", err)
+	}
+
+	page, err := s.DB.ListAuditEvents(ctx, query)
+	if err != nil {
+		log.Warnf("ListAuditEvents: %s", err)
+		return &proto.ListAuditEventsResponse{}, toStatus("ListAuditEvents", err)
+	}
+
+	resp := &proto.ListAuditEventsResponse{NextPageToken: page.NextPageToken}
+	for _, ev := range page.Events {
+		event, err := convert.AuditEvent(ev)
+		if err != nil {
+			log.Warnf("ListAuditEvents: %s", err)
+			return &proto.ListAuditEventsResponse{}, toStatus("ListAuditEvents", err)
+		}
+		resp.Events = append(resp.Events, event)
+	}
+	return resp, nil
+}
+
+func listAuditEventsQuery(req *proto.ListAuditEventsRequest) (domain.ListAuditEventsQuery, error) {
+	query := domain.ListAuditEventsQuery{
+		PageSize:  int(req.GetPageSize()),
+		PageToken: req.GetPageToken(),
+	}
+	if query.PageSize < 0 {
+		return query, invalidArgument("page_size", errors.New("page size must not be negative"))
+	}
+
+	filter := req.GetFilter()
+	if filter.GetTargetOid().GetValue() != "" {
+		oid, err := uuid.Parse(filter.GetTargetOid().GetValue())
+		if err != nil {
+			return query, invalidArgument("filter.target_oid", err)
+		}
+		query.Filter.TargetOid = oid
+	}
+	query.Filter.Actor = filter.GetActor()
+	query.Filter.Actions = filter.GetActions()
+	if filter.GetOccurredAfter() != nil {
+		query.Filter.OccurredAfter = filter.GetOccurredAfter().AsTime()
+	}
+	if filter.GetOccurredBefore() != nil {
+		query.Filter.OccurredBefore = filter.GetOccurredBefore().AsTime()
+	}
+	return query, nil
+}
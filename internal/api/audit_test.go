@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestServerAPI_ListAuditEvents(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		req       *proto.ListAuditEventsRequest
+		wantQuery *domain.ListAuditEventsQuery
+		wantCode  codes.Code
+	}{
+		{
+			name: "Positive case",
+			req: &proto.ListAuditEventsRequest{
+				Filter: &proto.AuditFilter{
+					TargetOid:     &proto.UUID{Value: oid.String()},
+					Actions:       []string{domain.ActionUserDeleted},
+					OccurredAfter: timestamppb.New(after),
+				},
+				PageSize:  10,
+				PageToken: "token",
+			},
+			wantQuery: &domain.ListAuditEventsQuery{
+				Filter: domain.AuditFilter{
+					TargetOid:     oid,
+					Actions:       []string{domain.ActionUserDeleted},
+					OccurredAfter: after,
+				},
+				PageSize:  10,
+				PageToken: "token",
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:      "No filter",
+			req:       &proto.ListAuditEventsRequest{},
+			wantQuery: &domain.ListAuditEventsQuery{},
+			wantCode:  codes.OK,
+		},
+		{
+			name:     "Malformed target oid",
+			req:      &proto.ListAuditEventsRequest{Filter: &proto.AuditFilter{TargetOid: &proto.UUID{Value: "abc"}}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Negative page size",
+			req:      &proto.ListAuditEventsRequest{PageSize: -1},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &domain.AuditPage{
+				Events: []*domain.AuditEvent{{
+					ID:        1,
+					TargetOid: oid,
+					Action:    domain.ActionUserDeleted,
+					Before:    map[string]any{"state": "USER_STATE_ACTIVE"},
+					After:     map[string]any{"state": "USER_STATE_DELETED"},
+				}},
+				NextPageToken: "next",
+			}
+			if tt.wantQuery != nil {
+				mockDB.On("ListAuditEvents", mock.Anything, *tt.wantQuery).Return(page, nil).Once()
+			}
+
+			got, err := s.ListAuditEvents(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.ListAuditEvents() code = %v, want %v", code, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			want := &proto.AuditEvent{
+				Id:        1,
+				TargetOid: &proto.UUID{Value: oid.String()},
+				Action:    domain.ActionUserDeleted,
+				Before:    &structpb.Struct{Fields: map[string]*structpb.Value{"state": structpb.NewStringValue("USER_STATE_ACTIVE")}},
+				After:     &structpb.Struct{Fields: map[string]*structpb.Value{"state": structpb.NewStringValue("USER_STATE_DELETED")}},
+			}
+			if len(got.Events) != 1 || !gproto.Equal(got.Events[0], want) || got.NextPageToken != "next" {
+				t.Errorf("ServerAPI.ListAuditEvents() = %v, want %v", got, page)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var errInvalidCredentials = status.Error(codes.Unauthenticated, "Login: invalid credentials")
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyHash spends the same time as a real password check so that
+// unknown logins can't be told apart by response time.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func (s *ServerAPI) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	if req.GetEmail() == "" && req.GetNickname() == "" {
+		return &proto.LoginResponse{}, toStatus("Login", invalidArgument("login", errors.New("email or nickname is required")))
+	}
+
+	creds, err := s.DB.GetCredentials(ctx, req.GetEmail(), req.GetNickname())
+	if errors.Is(err, domain.ErrNotFound) {
+		compareDummyHash(req.GetPassword())
+		log.Infof("Login: unknown user")
+		return &proto.LoginResponse{}, errInvalidCredentials
+	}
+	if err != nil {
+		log.Warnf("Login: %s", err)
+		return &proto.LoginResponse{}, toStatus("Login", err)
+	}
+
+	if err := checkCredentials(creds, req.GetPassword()); err != nil {
+		log.Infof("Login: user %s: %s", creds.Oid, err)
+		return &proto.LoginResponse{}, errInvalidCredentials
+	}
+
+	refresh, rt, err := s.newRefreshToken()
+	if err != nil {
+		log.Warnf("Login: %s", err)
+		return &proto.LoginResponse{}, toStatus("Login", err)
+	}
+	rt.FamilyID = uuid.New()
+	rt.UserOid = creds.Oid
+	if err := s.DB.CreateRefreshToken(ctx, rt); err != nil {
+		log.Warnf("Login: %s", err)
+		return &proto.LoginResponse{}, toStatus("Login", err)
+	}
+
+	tokens, err := s.tokenPair(creds.Oid, refresh, rt)
+	if err != nil {
+		log.Warnf("Login: %s", err)
+		return &proto.LoginResponse{}, toStatus("Login", err)
+	}
+
+	return &proto.LoginResponse{
+		Oid:    &proto.UUID{Value: creds.Oid.String()},
+		Tokens: tokens,
+	}, nil
+}
+
+func (s *ServerAPI) Refresh(ctx context.Context, req *proto.RefreshRequest) (*proto.RefreshResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return &proto.RefreshResponse{}, toStatus("Refresh", invalidArgument("refresh_token", errors.New("refresh token is required")))
+	}
+
+	refresh, next, err := s.newRefreshToken()
+	if err != nil {
+		log.Warnf("Refresh: %s", err)
+		return &proto.RefreshResponse{}, toStatus("Refresh", err)
+	}
+	err = s.DB.RotateRefreshToken(ctx, token.Hash(req.GetRefreshToken()), next)
+	if errors.Is(err, domain.ErrUnauthenticated) {
+		log.Infof("Refresh: %s", err)
+		return &proto.RefreshResponse{}, status.Error(codes.Unauthenticated, "Refresh: invalid refresh token")
+	}
+	if err != nil {
+		log.Warnf("Refresh: %s", err)
+		return &proto.RefreshResponse{}, toStatus("Refresh", err)
+	}
+
+	tokens, err := s.tokenPair(next.UserOid, refresh, next)
+	if err != nil {
+		log.Warnf("Refresh: %s", err)
+		return &proto.RefreshResponse{}, toStatus("Refresh", err)
+	}
+
+	return &proto.RefreshResponse{Tokens: tokens}, nil
+}
+
+func (s *ServerAPI) Revoke(ctx context.Context, req *proto.RevokeRequest) (*proto.RevokeResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return &proto.RevokeResponse{IsOk: false}, toStatus("Revoke", invalidArgument("refresh_token", errors.New("refresh token is required")))
+	}
+
+	err := s.DB.RevokeRefreshToken(ctx, token.Hash(req.GetRefreshToken()))
+	if err != nil {
+		log.Warnf("Revoke: %s", err)
+		return &proto.RevokeResponse{IsOk: false}, toStatus("Revoke", err)
+	}
+
+	return &proto.RevokeResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) GetPublicKeys(ctx context.Context, req *proto.GetPublicKeysRequest) (*proto.GetPublicKeysResponse, error) {
+	var keys []*proto.JsonWebKey
+	for _, k := range s.Tokens.PublicKeys() {
+		keys = append(keys, &proto.JsonWebKey{
+			Kty: k.Kty,
+			Crv: k.Crv,
+			X:   k.X,
+			Kid: k.Kid,
+			Alg: k.Alg,
+			Use: k.Use,
+		})
+	}
+	return &proto.GetPublicKeysResponse{Keys: keys}, nil
+}
+
+// newRefreshToken generates a refresh token and the record to store for it.
+// The caller sets the family and user of the record.
+func (s *ServerAPI) newRefreshToken() (string, *domain.RefreshToken, error) {
+	refresh, hash, expires, err := s.Tokens.NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+	return refresh, &domain.RefreshToken{TokenHash: hash, ExpiresAt: expires}, nil
+}
+
+// tokenPair mints an access token for oid and bundles it with refresh.
+func (s *ServerAPI) tokenPair(oid uuid.UUID, refresh string, rt *domain.RefreshToken) (*proto.TokenPair, error) {
+	access, expires, err := s.Tokens.IssueAccessToken(oid)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.TokenPair{
+		AccessToken:           access,
+		AccessTokenExpiresAt:  timestamppb.New(expires),
+		RefreshToken:          refresh,
+		RefreshTokenExpiresAt: timestamppb.New(rt.ExpiresAt),
+	}, nil
+}
+
+// caller describes the principal of the call for logs.
+func caller(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.String()
+	}
+	return "anonymous caller"
+}
+
+// checkCredentials verifies password against the stored hash and refuses
+// users that are not allowed to log in.
+func checkCredentials(creds *domain.Credentials, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(password)); err != nil {
+		return fmt.Errorf("wrong password: %w", domain.ErrUnauthenticated)
+	}
+	if !creds.CanLogIn(time.Now()) {
+		return fmt.Errorf("user is in state %d: %w", creds.State, domain.ErrUnauthenticated)
+	}
+	return nil
+}
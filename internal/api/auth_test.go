@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerAPI_Login(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB, Tokens: newTestTokens(t)}
+
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Test123."), bcrypt.MinCost)
+	creds := func(state domain.State) *domain.Credentials {
+		return &domain.Credentials{Oid: oid, PasswordHash: string(hash), State: state}
+	}
+
+	tests := []struct {
+		name      string
+		req       *proto.LoginRequest
+		needsMock bool
+		mockResp  *domain.Credentials
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "Test123."}, needsMock: true, mockResp: creds(domain.Active), wantCode: codes.OK},
+		{name: "Nickname login", req: &proto.LoginRequest{Login: &proto.LoginRequest_Nickname{Nickname: "test"}, Password: "Test123."}, needsMock: true, mockResp: creds(domain.Active), wantCode: codes.OK},
+		{name: "Wrong password", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "wrong"}, needsMock: true, mockResp: creds(domain.Active), wantCode: codes.Unauthenticated},
+		{name: "Unknown user", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "Test123."}, needsMock: true, mockErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.Unauthenticated},
+		{name: "Banned user", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "Test123."}, needsMock: true, mockResp: creds(domain.Banned), wantCode: codes.Unauthenticated},
+		{name: "Suspended user", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "Test123."}, needsMock: true, mockResp: &domain.Credentials{Oid: oid, PasswordHash: string(hash), State: domain.Suspended, SuspendedUntil: time.Now().Add(time.Hour)}, wantCode: codes.Unauthenticated},
+		{name: "Deleted user", req: &proto.LoginRequest{Login: &proto.LoginRequest_Email{Email: "test@example.com"}, Password: "Test123."}, needsMock: true, mockResp: creds(domain.Deleted), wantCode: codes.Unauthenticated},
+		{name: "No login", req: &proto.LoginRequest{Password: "Test123."}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("GetCredentials", mock.Anything, tt.req.GetEmail(), tt.req.GetNickname()).Return(tt.mockResp, tt.mockErr).Once()
+			}
+			if tt.wantCode == codes.OK {
+				mockDB.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(rt *domain.RefreshToken) bool {
+					return rt.UserOid == oid && rt.FamilyID != uuid.Nil && rt.TokenHash != ""
+				})).Return(nil).Once()
+			}
+
+			got, err := s.Login(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.Login() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK {
+				if got.GetOid().GetValue() != oid.String() {
+					t.Errorf("ServerAPI.Login() oid = %v, want %v", got.GetOid().GetValue(), oid)
+				}
+				claims, err := s.Tokens.VerifyAccessToken(got.GetTokens().GetAccessToken())
+				if err != nil || claims.Subject != oid.String() {
+					t.Errorf("ServerAPI.Login() access token claims = %v, err = %v", claims, err)
+				}
+				if got.GetTokens().GetRefreshToken() == "" {
+					t.Errorf("ServerAPI.Login() returned no refresh token")
+				}
+			}
+			if tt.wantCode == codes.Unauthenticated && status.Convert(err).Message() != status.Convert(errInvalidCredentials).Message() {
+				t.Errorf("ServerAPI.Login() message = %q, want uniform message", status.Convert(err).Message())
+			}
+		})
+	}
+}
+
+func newTestTokens(t *testing.T) *token.Manager {
+	key, err := token.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.NewManager(token.Config{
+		Issuer:     "test",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+		SigningKey: key,
+	})
+}
+
+func TestServerAPI_Refresh(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB, Tokens: newTestTokens(t)}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.RefreshRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.RefreshRequest{RefreshToken: "token"}, needsMock: true, wantCode: codes.OK},
+		{name: "Reused token", req: &proto.RefreshRequest{RefreshToken: "token"}, needsMock: true, mockErr: fmt.Errorf("reused: %w", domain.ErrUnauthenticated), wantCode: codes.Unauthenticated},
+		{name: "DB error", req: &proto.RefreshRequest{RefreshToken: "token"}, needsMock: true, mockErr: errors.New("error"), wantCode: codes.Internal},
+		{name: "Empty token", req: &proto.RefreshRequest{}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("RotateRefreshToken", mock.Anything, token.Hash(tt.req.GetRefreshToken()), mock.Anything).Run(func(args mock.Arguments) {
+					args.Get(2).(*domain.RefreshToken).UserOid = oid
+				}).Return(tt.mockErr).Once()
+			}
+
+			got, err := s.Refresh(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.Refresh() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK {
+				claims, err := s.Tokens.VerifyAccessToken(got.GetTokens().GetAccessToken())
+				if err != nil || claims.Subject != oid.String() {
+					t.Errorf("ServerAPI.Refresh() access token claims = %v, err = %v", claims, err)
+				}
+			}
+		})
+	}
+}
+
+func TestServerAPI_Revoke(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+
+	tests := []struct {
+		name     string
+		token    string
+		mockErr  error
+		wantCode codes.Code
+	}{
+		// Unknown and revoked tokens are revoked successfully as well.
+		{name: "Positive case", token: "refresh", wantCode: codes.OK},
+		{name: "No token", wantCode: codes.InvalidArgument},
+		{name: "Database down", token: "refresh", mockErr: fmt.Errorf("query: %w", domain.ErrUnavailable), wantCode: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.token != "" {
+				mockDB.On("RevokeRefreshToken", mock.Anything, token.Hash(tt.token)).Return(tt.mockErr).Once()
+			}
+
+			got, err := s.Revoke(context.Background(), &proto.RevokeRequest{RefreshToken: tt.token})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.Revoke() code = %v, want %v", code, tt.wantCode)
+			}
+			if got.GetIsOk() != (tt.wantCode == codes.OK) {
+				t.Errorf("ServerAPI.Revoke() is_ok = %v", got.GetIsOk())
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package convert maps the audit events, webhooks and deliveries served by
+// the API to the protobuf messages of UserService and back. Users and user
+// events, which are also published, are mapped by the encode package.
+package convert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Users(users []*domain.User) []*proto.UserInfo {
+	infos := make([]*proto.UserInfo, 0, len(users))
+	for _, u := range users {
+		infos = append(infos, encode.User(u))
+	}
+	return infos
+}
+
+func AuditEvent(ev *domain.AuditEvent) (*proto.AuditEvent, error) {
+	before, err := jsonStruct(ev.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := jsonStruct(ev.After)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.AuditEvent{
+		Id:         ev.ID,
+		OccurredAt: timestamp(ev.OccurredAt),
+		Actor:      ev.Actor,
+		TargetOid:  encode.UUID(ev.TargetOid),
+		Action:     ev.Action,
+		Before:     before,
+		After:      after,
+		RequestId:  ev.RequestID,
+		Peer:       ev.Peer,
+	}, nil
+}
+
+func jsonStruct(m map[string]any) (*structpb.Struct, error) {
+	if m == nil {
+		return nil, nil
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode audit event: %w", err)
+	}
+	return s, nil
+}
+
+func Webhook(w *domain.Webhook) *proto.Webhook {
+	webhook := &proto.Webhook{
+		Id:        encode.UUID(w.ID),
+		Url:       w.URL,
+		CreatedAt: timestamp(w.CreatedAt),
+	}
+	for _, t := range w.EventTypes {
+		webhook.EventTypes = append(webhook.EventTypes, encode.EventType(t))
+	}
+	return webhook
+}
+
+// WebhookDelivery decodes the event sent by the delivery, which is the
+// payload built by encode.Marshal.
+func WebhookDelivery(d *domain.WebhookDelivery) (*proto.WebhookDelivery, error) {
+	ev, err := encode.Unmarshal(d.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.WebhookDelivery{
+		Id:             d.ID,
+		WebhookId:      encode.UUID(d.WebhookID),
+		Event:          ev,
+		State:          DeliveryState(d.State),
+		Attempts:       int32(d.Attempts),
+		CreatedAt:      timestamp(d.CreatedAt),
+		NextAttemptAt:  timestamp(d.NextAttemptAt),
+		LastAttemptAt:  timestamp(d.LastAttemptAt),
+		LastStatusCode: int32(d.LastStatusCode),
+		LastError:      d.LastError,
+	}, nil
+}
+
+func DeliveryState(state domain.DeliveryState) proto.WebhookDeliveryState {
+	switch state {
+	case domain.DeliveryPending:
+		return proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_PENDING
+	case domain.DeliveryDelivered:
+		return proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_DELIVERED
+	case domain.DeliveryDeadLetter:
+		return proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_DEAD_LETTER
+	}
+	return proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_UNSPECIFIED
+}
+
+func DeliveryStateFromProto(state proto.WebhookDeliveryState) (domain.DeliveryState, error) {
+	switch state {
+	case proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_PENDING:
+		return domain.DeliveryPending, nil
+	case proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_DELIVERED:
+		return domain.DeliveryDelivered, nil
+	case proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_DEAD_LETTER:
+		return domain.DeliveryDeadLetter, nil
+	}
+	return 0, fmt.Errorf("unknown delivery state %v", state)
+}
+
+// timestamp leaves zero times unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,33 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	ev := &domain.UserEvent{Offset: 3, Type: domain.EventDeleted, User: &domain.User{Nickname: "john"}}
+	payload, err := encode.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := WebhookDelivery(&domain.WebhookDelivery{ID: 1, Payload: payload, State: domain.DeliveryPending})
+	if err != nil {
+		t.Fatalf("WebhookDelivery() error = %v", err)
+	}
+	if !gproto.Equal(got.Event, encode.UserEvent(ev)) || got.State != proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_PENDING {
+		t.Errorf("WebhookDelivery() = %v", got)
+	}
+	if got.LastAttemptAt != nil {
+		t.Errorf("WebhookDelivery() last attempt = %v, want unset", got.LastAttemptAt)
+	}
+
+	if _, err := WebhookDelivery(&domain.WebhookDelivery{Payload: []byte("not json")}); err == nil {
+		t.Errorf("WebhookDelivery() expected error for malformed payload")
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts an error returned by the domain layer into a gRPC status
+// error. Field errors are reported as google.rpc.BadRequest field violations.
+// Internal failures are not described to the client, they are only logged.
+func toStatus(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := codeOf(err)
+	msg := fmt.Sprintf("%s: %s", method, err)
+	switch code {
+	case codes.Internal:
+		msg = fmt.Sprintf("%s: internal error", method)
+	case codes.Unavailable:
+		msg = fmt.Sprintf("%s: service is temporarily unavailable", method)
+	}
+
+	st := status.New(code, msg)
+
+	var fieldErr *domain.FieldError
+	if errors.As(err, &fieldErr) {
+		withDetails, detailsErr := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: fieldErr.Field, Description: fieldErr.Description},
+			},
+		})
+		if detailsErr == nil {
+			st = withDetails
+		}
+	}
+
+	return st.Err()
+}
+
+func codeOf(err error) codes.Code {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, domain.ErrInvalidArgument):
+		return codes.InvalidArgument
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return codes.PermissionDenied
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, domain.ErrConflict):
+		return codes.Aborted
+	case errors.Is(err, domain.ErrFailedPrecondition):
+		return codes.FailedPrecondition
+	case errors.Is(err, domain.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// invalidArgument builds a field error for request validation failures.
+func invalidArgument(field string, err error) error {
+	return domain.NewFieldError(domain.ErrInvalidArgument, field, err.Error())
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  codes.Code
+		wantField string
+	}{
+		{name: "Not found", err: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "Already exists", err: domain.NewFieldError(domain.ErrAlreadyExists, "email", "taken"), wantCode: codes.AlreadyExists, wantField: "email"},
+		{name: "Invalid argument", err: invalidArgument("oid", errors.New("bad uuid")), wantCode: codes.InvalidArgument, wantField: "oid"},
+		{name: "Unavailable", err: fmt.Errorf("%w: conn refused", domain.ErrUnavailable), wantCode: codes.Unavailable},
+		{name: "Conflict", err: fmt.Errorf("etag mismatch: %w", domain.ErrConflict), wantCode: codes.Aborted},
+		{name: "Permission denied", err: domain.ErrPermissionDenied, wantCode: codes.PermissionDenied},
+		{name: "Deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantCode: codes.DeadlineExceeded},
+		{name: "Unknown error", err: errors.New("boom"), wantCode: codes.Internal},
+		{name: "Status passthrough", err: status.Error(codes.Aborted, "aborted"), wantCode: codes.Aborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus("Test", tt.err))
+			if st.Code() != tt.wantCode {
+				t.Errorf("toStatus() code = %v, want %v", st.Code(), tt.wantCode)
+			}
+			var field string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok && len(br.FieldViolations) > 0 {
+					field = br.FieldViolations[0].Field
+				}
+			}
+			if field != tt.wantField {
+				t.Errorf("toStatus() field violation = %q, want %q", field, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/notify"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hashPassword checks password against the password policy and returns its
+// bcrypt hash. field names the request field in validation errors.
+func hashPassword(field, password string) (string, error) {
+	if err := сheckPassword(password); err != nil {
+		return "", invalidArgument(field, err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate hash for password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (s *ServerAPI) ChangePassword(ctx context.Context, req *proto.ChangePasswordRequest) (*proto.ChangePasswordResponse, error) {
+	oid, err := uuid.Parse(req.GetOid().GetValue())
+	if err != nil {
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", invalidArgument("oid", err))
+	}
+	if req.GetOldPassword() == req.GetNewPassword() {
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", invalidArgument("new_password", errors.New("new password must differ from the old one")))
+	}
+
+	creds, err := s.DB.GetCredentialsByID(ctx, oid)
+	if err != nil {
+		log.Warnf("ChangePassword: %s", err)
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(req.GetOldPassword())); err != nil {
+		log.Infof("ChangePassword: user %s: wrong password", oid)
+		return &proto.ChangePasswordResponse{IsOk: false}, status.Error(codes.Unauthenticated, "ChangePassword: old password is incorrect")
+	}
+	// Checked after the password, so that only the owner of the account
+	// learns that its state is the reason.
+	if !creds.CanLogIn(time.Now()) {
+		log.Infof("ChangePassword: user %s is in state %d", oid, creds.State)
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", fmt.Errorf("account is %s: %w", creds.State, domain.ErrFailedPrecondition))
+	}
+
+	hash, err := hashPassword("new_password", req.GetNewPassword())
+	if err != nil {
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", err)
+	}
+
+	if err := s.DB.SetPassword(ctx, oid, hash); err != nil {
+		log.Warnf("ChangePassword: %s", err)
+		return &proto.ChangePasswordResponse{IsOk: false}, toStatus("ChangePassword", err)
+	}
+
+	log.Infof("Password of user %s was changed", oid)
+	return &proto.ChangePasswordResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) SetPassword(ctx context.Context, req *proto.SetPasswordRequest) (*proto.SetPasswordResponse, error) {
+	oid, err := uuid.Parse(req.GetOid().GetValue())
+	if err != nil {
+		return &proto.SetPasswordResponse{IsOk: false}, toStatus("SetPassword", invalidArgument("oid", err))
+	}
+
+	hash, err := hashPassword("new_password", req.GetNewPassword())
+	if err != nil {
+		return &proto.SetPasswordResponse{IsOk: false}, toStatus("SetPassword", err)
+	}
+
+	if err := s.DB.SetPassword(ctx, oid, hash); err != nil {
+		log.Warnf("SetPassword: %s", err)
+		return &proto.SetPasswordResponse{IsOk: false}, toStatus("SetPassword", err)
+	}
+
+	log.Infof("Password of user %s was reset by %s", oid, caller(ctx))
+	return &proto.SetPasswordResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) RequestPasswordReset(ctx context.Context, req *proto.RequestPasswordResetRequest) (*proto.RequestPasswordResetResponse, error) {
+	if req.GetEmail() == "" {
+		return &proto.RequestPasswordResetResponse{IsOk: false}, toStatus("RequestPasswordReset", invalidArgument("email", errors.New("email is required")))
+	}
+
+	// The response is sent before the user is looked up and is the same
+	// whether or not the email is registered, so that neither it nor its
+	// timing tells callers which emails belong to users.
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		s.sendResetToken(ctx, req.GetEmail())
+	}()
+	return &proto.RequestPasswordResetResponse{IsOk: true}, nil
+}
+
+// sendResetToken creates a password reset token for the user with the email
+// and sends it to them. The caller got its response already, so failures are
+// only logged.
+func (s *ServerAPI) sendResetToken(ctx context.Context, email string) {
+	creds, err := s.DB.GetCredentials(ctx, email, "")
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Infof("RequestPasswordReset: unknown email")
+		return
+	}
+	if err != nil {
+		log.Warnf("RequestPasswordReset: %s", err)
+		return
+	}
+	if creds.State == domain.Banned || creds.State == domain.Deleted {
+		log.Infof("RequestPasswordReset: user %s is in state %d", creds.Oid, creds.State)
+		return
+	}
+
+	reset, hash, err := token.NewOpaque()
+	if err != nil {
+		log.Warnf("RequestPasswordReset: unable to generate token: %s", err)
+		return
+	}
+	expires := time.Now().Add(s.ResetTokenTTL)
+	if err := s.DB.CreateResetToken(ctx, creds.Oid, hash, expires); err != nil {
+		log.Warnf("RequestPasswordReset: %s", err)
+		return
+	}
+
+	err = s.Notifier.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Use this token to reset your password: %s\nIt expires at %s.",
+			reset, expires.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		log.Warnf("RequestPasswordReset: unable to send token to user %s: %s", creds.Oid, err)
+	}
+}
+
+func (s *ServerAPI) CompletePasswordReset(ctx context.Context, req *proto.CompletePasswordResetRequest) (*proto.CompletePasswordResetResponse, error) {
+	if req.GetToken() == "" {
+		return &proto.CompletePasswordResetResponse{IsOk: false}, toStatus("CompletePasswordReset", invalidArgument("token", errors.New("token is required")))
+	}
+
+	hash, err := hashPassword("new_password", req.GetNewPassword())
+	if err != nil {
+		return &proto.CompletePasswordResetResponse{IsOk: false}, toStatus("CompletePasswordReset", err)
+	}
+
+	oid, err := s.DB.ResetPassword(ctx, token.Hash(req.GetToken()), hash)
+	if err != nil {
+		log.Warnf("CompletePasswordReset: %s", err)
+		return &proto.CompletePasswordResetResponse{IsOk: false}, toStatus("CompletePasswordReset", err)
+	}
+
+	log.Infof("Password of user %s was reset with a token", oid)
+	return &proto.CompletePasswordResetResponse{IsOk: true}, nil
+}
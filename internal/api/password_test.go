@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/sosshik/grpc-user-managment/internal/notify"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerAPI_ChangePassword(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Test123."), bcrypt.MinCost)
+	banned := domain.Banned
+	req := func(old, new string) *proto.ChangePasswordRequest {
+		return &proto.ChangePasswordRequest{Oid: &proto.UUID{Value: oid.String()}, OldPassword: old, NewPassword: new}
+	}
+
+	tests := []struct {
+		name        string
+		req         *proto.ChangePasswordRequest
+		state       *domain.State
+		needsCreds  bool
+		credsErr    error
+		needsUpdate bool
+		updateErr   error
+		wantCode    codes.Code
+	}{
+		{name: "Positive case", req: req("Test123.", "Test1234!"), needsCreds: true, needsUpdate: true, wantCode: codes.OK},
+		{name: "Wrong old password", req: req("Wrong123.", "Test1234!"), needsCreds: true, wantCode: codes.Unauthenticated},
+		{name: "Banned user", req: req("Test123.", "Test1234!"), state: &banned, needsCreds: true, wantCode: codes.FailedPrecondition},
+		{name: "Banned user with wrong password", req: req("Wrong123.", "Test1234!"), state: &banned, needsCreds: true, wantCode: codes.Unauthenticated},
+		{name: "Weak new password", req: req("Test123.", "weak"), needsCreds: true, wantCode: codes.InvalidArgument},
+		{name: "Same password", req: req("Test123.", "Test123."), wantCode: codes.InvalidArgument},
+		{name: "Unknown user", req: req("Test123.", "Test1234!"), needsCreds: true, credsErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "DB error", req: req("Test123.", "Test1234!"), needsCreds: true, needsUpdate: true, updateErr: errors.New("error"), wantCode: codes.Internal},
+		{name: "Wrong OID", req: &proto.ChangePasswordRequest{Oid: &proto.UUID{Value: "bad"}}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsCreds {
+				creds := &domain.Credentials{Oid: oid, PasswordHash: string(hash), State: domain.Active}
+				if tt.state != nil {
+					creds.State = *tt.state
+				}
+				mockDB.On("GetCredentialsByID", mock.Anything, oid).Return(creds, tt.credsErr).Once()
+			}
+			if tt.needsUpdate {
+				mockDB.On("SetPassword", mock.Anything, oid, mock.MatchedBy(func(h string) bool {
+					return bcrypt.CompareHashAndPassword([]byte(h), []byte(tt.req.GetNewPassword())) == nil
+				})).Return(tt.updateErr).Once()
+			}
+
+			_, err := s.ChangePassword(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.ChangePassword() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServerAPI_SetPassword(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.SetPasswordRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.SetPasswordRequest{Oid: &proto.UUID{Value: oid.String()}, NewPassword: "Test123."}, needsMock: true, wantCode: codes.OK},
+		{name: "Unknown user", req: &proto.SetPasswordRequest{Oid: &proto.UUID{Value: oid.String()}, NewPassword: "Test123."}, needsMock: true, mockErr: fmt.Errorf("user %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "Weak password", req: &proto.SetPasswordRequest{Oid: &proto.UUID{Value: oid.String()}, NewPassword: "weak"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("SetPassword", mock.Anything, oid, mock.Anything).Return(tt.mockErr).Once()
+			}
+
+			_, err := s.SetPassword(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.SetPassword() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+type fakeNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (f *fakeNotifier) Send(ctx context.Context, msg notify.Message) error {
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func TestServerAPI_RequestPasswordReset(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name        string
+		credsState  domain.State
+		credsErr    error
+		needsToken  bool
+		notifyErr   error
+		wantCode    codes.Code
+		wantMessage bool
+	}{
+		{name: "Positive case", credsState: domain.Active, needsToken: true, wantCode: codes.OK, wantMessage: true},
+		{name: "Unknown email", credsErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.OK},
+		{name: "Banned user", credsState: domain.Banned, wantCode: codes.OK},
+		{name: "Database error", credsErr: errors.New("connection refused"), wantCode: codes.OK},
+		{name: "Notifier error", credsState: domain.Active, needsToken: true, notifyErr: errors.New("smtp down"), wantCode: codes.OK, wantMessage: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &fakeNotifier{err: tt.notifyErr}
+			s := ServerAPI{DB: mockDB, Notifier: notifier, ResetTokenTTL: time.Hour}
+
+			var creds *domain.Credentials
+			if tt.credsErr == nil {
+				creds = &domain.Credentials{Oid: oid, State: tt.credsState}
+			}
+			mockDB.On("GetCredentials", mock.Anything, "test@example.com", "").Return(creds, tt.credsErr).Once()
+			var storedHash string
+			if tt.needsToken {
+				mockDB.On("CreateResetToken", mock.Anything, oid, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					storedHash = args.String(2)
+				}).Return(nil).Once()
+			}
+
+			resp, err := s.RequestPasswordReset(context.Background(), &proto.RequestPasswordResetRequest{Email: "test@example.com"})
+			if code := status.Code(err); code != tt.wantCode || !resp.GetIsOk() {
+				t.Fatalf("ServerAPI.RequestPasswordReset() code = %v, ok = %v, want %v", code, resp.GetIsOk(), tt.wantCode)
+			}
+			s.Wait()
+			if (len(notifier.sent) == 1) != tt.wantMessage {
+				t.Fatalf("ServerAPI.RequestPasswordReset() sent %d messages, want message %v", len(notifier.sent), tt.wantMessage)
+			}
+			if tt.wantMessage {
+				msg := notifier.sent[0]
+				if msg.To != "test@example.com" {
+					t.Errorf("message sent to %q", msg.To)
+				}
+				_, rest, _ := strings.Cut(msg.Body, "password: ")
+				sentToken, _, _ := strings.Cut(rest, "\n")
+				if token.Hash(sentToken) != storedHash {
+					t.Errorf("message body %q doesn't contain the stored token", msg.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestServerAPI_CompletePasswordReset(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.CompletePasswordResetRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.CompletePasswordResetRequest{Token: "token", NewPassword: "Test123."}, needsMock: true, wantCode: codes.OK},
+		{name: "Used token", req: &proto.CompletePasswordResetRequest{Token: "token", NewPassword: "Test123."}, needsMock: true, mockErr: domain.NewFieldError(domain.ErrInvalidArgument, "token", "reset token is invalid or expired"), wantCode: codes.InvalidArgument},
+		{name: "Weak password", req: &proto.CompletePasswordResetRequest{Token: "token", NewPassword: "weak"}, wantCode: codes.InvalidArgument},
+		{name: "No token", req: &proto.CompletePasswordResetRequest{NewPassword: "Test123."}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("ResetPassword", mock.Anything, token.Hash(tt.req.GetToken()), mock.Anything).Return(oid, tt.mockErr).Once()
+			}
+
+			_, err := s.CompletePasswordReset(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.CompletePasswordReset() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+)
+
+func (s *ServerAPI) GetRoles(ctx context.Context, req *proto.GetRolesRequest) (*proto.GetRolesResponse, error) {
+	oid, err := uuid.Parse(req.GetOid().GetValue())
+	if err != nil {
+		return &proto.GetRolesResponse{}, toStatus("GetRoles", invalidArgument("oid", err))
+	}
+
+	roles, err := s.DB.GetRoles(ctx, oid)
+	if err != nil {
+		log.Warnf("GetRoles: %s", err)
+		return &proto.GetRolesResponse{}, toStatus("GetRoles", err)
+	}
+
+	resp := &proto.GetRolesResponse{}
+	for _, role := range roles {
+		resp.Roles = append(resp.Roles, roleToProto(role))
+	}
+	return resp, nil
+}
+
+func (s *ServerAPI) GrantRole(ctx context.Context, req *proto.GrantRoleRequest) (*proto.GrantRoleResponse, error) {
+	oid, role, err := roleRequest(req.GetOid(), req.GetRole())
+	if err != nil {
+		return &proto.GrantRoleResponse{IsOk: false}, toStatus("GrantRole", err)
+	}
+
+	if err := s.DB.GrantRole(ctx, oid, role); err != nil {
+		log.Warnf("GrantRole: %s", err)
+		return &proto.GrantRoleResponse{IsOk: false}, toStatus("GrantRole", err)
+	}
+
+	log.Infof("Role %v was granted to user %s by %s", req.GetRole(), oid, caller(ctx))
+	return &proto.GrantRoleResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) RevokeRole(ctx context.Context, req *proto.RevokeRoleRequest) (*proto.RevokeRoleResponse, error) {
+	oid, role, err := roleRequest(req.GetOid(), req.GetRole())
+	if err != nil {
+		return &proto.RevokeRoleResponse{IsOk: false}, toStatus("RevokeRole", err)
+	}
+	// Keeps the last admin from locking everybody out.
+	if p, ok := auth.FromContext(ctx); ok && p.Oid == oid && role == domain.RoleAdmin {
+		return &proto.RevokeRoleResponse{IsOk: false}, toStatus("RevokeRole", invalidArgument("role", errors.New("admins can't revoke their own admin role")))
+	}
+
+	if err := s.DB.RevokeRole(ctx, oid, role); err != nil {
+		log.Warnf("RevokeRole: %s", err)
+		return &proto.RevokeRoleResponse{IsOk: false}, toStatus("RevokeRole", err)
+	}
+
+	log.Infof("Role %v was revoked from user %s by %s", req.GetRole(), oid, caller(ctx))
+	return &proto.RevokeRoleResponse{IsOk: true}, nil
+}
+
+// checkIncludeDeleted returns ErrPermissionDenied if a caller other than an
+// admin asks for deleted users.
+func checkIncludeDeleted(ctx context.Context, includeDeleted bool) error {
+	if !includeDeleted {
+		return nil
+	}
+	if p, ok := auth.FromContext(ctx); ok && p.HasRole(domain.RoleAdmin) {
+		return nil
+	}
+	return fmt.Errorf("include_deleted is admin only: %w", domain.ErrPermissionDenied)
+}
+
+func roleRequest(protoOid *proto.UUID, protoRole proto.Role) (uuid.UUID, domain.Role, error) {
+	oid, err := uuid.Parse(protoOid.GetValue())
+	if err != nil {
+		return uuid.Nil, 0, invalidArgument("oid", err)
+	}
+	role, err := roleFromProto(protoRole)
+	if err != nil {
+		return uuid.Nil, 0, invalidArgument("role", err)
+	}
+	return oid, role, nil
+}
+
+func roleFromProto(role proto.Role) (domain.Role, error) {
+	switch role {
+	case proto.Role_ROLE_USER:
+		return domain.RoleUser, nil
+	case proto.Role_ROLE_SUPPORT:
+		return domain.RoleSupport, nil
+	case proto.Role_ROLE_ADMIN:
+		return domain.RoleAdmin, nil
+	}
+	return 0, fmt.Errorf("unknown role %v", role)
+}
+
+func roleToProto(role domain.Role) proto.Role {
+	switch role {
+	case domain.RoleUser:
+		return proto.Role_ROLE_USER
+	case domain.RoleSupport:
+		return proto.Role_ROLE_SUPPORT
+	case domain.RoleAdmin:
+		return proto.Role_ROLE_ADMIN
+	}
+	return proto.Role_ROLE_UNSPECIFIED
+}
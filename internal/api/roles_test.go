@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerAPI_GetRoles(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	mockDB.On("GetRoles", mock.Anything, oid).Return([]domain.Role{domain.RoleUser, domain.RoleAdmin}, nil).Once()
+	got, err := s.GetRoles(context.Background(), &proto.GetRolesRequest{Oid: &proto.UUID{Value: oid.String()}})
+	if err != nil {
+		t.Fatalf("ServerAPI.GetRoles() error = %v", err)
+	}
+	want := []proto.Role{proto.Role_ROLE_USER, proto.Role_ROLE_ADMIN}
+	if !reflect.DeepEqual(got.GetRoles(), want) {
+		t.Errorf("ServerAPI.GetRoles() = %v, want %v", got.GetRoles(), want)
+	}
+}
+
+func TestServerAPI_GrantRole(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.GrantRoleRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.GrantRoleRequest{Oid: &proto.UUID{Value: oid.String()}, Role: proto.Role_ROLE_SUPPORT}, needsMock: true, wantCode: codes.OK},
+		{name: "Unknown user", req: &proto.GrantRoleRequest{Oid: &proto.UUID{Value: oid.String()}, Role: proto.Role_ROLE_SUPPORT}, needsMock: true, mockErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "Unspecified role", req: &proto.GrantRoleRequest{Oid: &proto.UUID{Value: oid.String()}}, wantCode: codes.InvalidArgument},
+		{name: "Wrong UUID", req: &proto.GrantRoleRequest{Oid: &proto.UUID{Value: "abc"}, Role: proto.Role_ROLE_ADMIN}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("GrantRole", mock.Anything, oid, domain.RoleSupport).Return(tt.mockErr).Once()
+			}
+
+			_, err := s.GrantRole(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.GrantRole() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServerAPI_RevokeRole(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	admin := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	other := uuid.MustParse("0b7e9f3c-3c1d-4f7a-9a57-0d2f3f1b6a11")
+	ctx := auth.NewContext(context.Background(), &auth.Principal{Oid: admin, Roles: []domain.Role{domain.RoleAdmin}})
+
+	tests := []struct {
+		name      string
+		oid       uuid.UUID
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", oid: other, needsMock: true, wantCode: codes.OK},
+		{name: "DB error", oid: other, needsMock: true, mockErr: errors.New("error"), wantCode: codes.Internal},
+		{name: "Own admin role", oid: admin, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("RevokeRole", mock.Anything, tt.oid, domain.RoleAdmin).Return(tt.mockErr).Once()
+			}
+
+			_, err := s.RevokeRole(ctx, &proto.RevokeRoleRequest{Oid: &proto.UUID{Value: tt.oid.String()}, Role: proto.Role_ROLE_ADMIN})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.RevokeRole() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
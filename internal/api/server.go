@@ -4,56 +4,107 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/api/convert"
 	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	"github.com/sosshik/grpc-user-managment/internal/notify"
+	"github.com/sosshik/grpc-user-managment/internal/token"
 	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
-	"golang.org/x/crypto/bcrypt"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type ServerAPI struct {
 	proto.UnimplementedUserServiceServer
-	DB domain.DomainInterface
+	DB       domain.DomainInterface
+	Tokens   *token.Manager
+	Notifier notify.Notifier
+	// ResetTokenTTL is the lifetime of password reset tokens.
+	ResetTokenTTL time.Duration
+	// VerificationTokenTTL is the lifetime of email verification tokens.
+	VerificationTokenTTL time.Duration
+	// Events wakes up WatchUsers streams when the relay publishes events.
+	Events *events.Broker
+	// WatchPollInterval is how often WatchUsers streams check the outbox
+	// for events published elsewhere, one second if zero.
+	WatchPollInterval time.Duration
+	// Stopping is closed when the server shuts down, ending open WatchUsers
+	// streams so that clients reconnect to another instance.
+	Stopping <-chan struct{}
+
+	// background tracks work calls leave running after they respond.
+	background sync.WaitGroup
 }
 
-func (s *ServerAPI) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.CreateUserResponse, error) {
-	user := req.GetUser()
+// Wait blocks until work that calls left running after responding, such as
+// sending password reset and verification tokens, is done.
+func (s *ServerAPI) Wait() {
+	s.background.Wait()
+}
 
-	if err := сheckPassword(req.GetPassword()); err != nil {
-		return &proto.CreateUserResponse{}, fmt.Errorf("CreateUser: %w", err)
+func (s *ServerAPI) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.CreateUserResponse, error) {
+	info := req.GetUser()
+	if info == nil {
+		return &proto.CreateUserResponse{}, toStatus("CreateUser", invalidArgument("user", errors.New("user is required")))
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.GetPassword()), bcrypt.DefaultCost)
-	if err != nil {
-		log.Warnf("CreateUser - unable to generate hash for password: %s", err)
-		return &proto.CreateUserResponse{}, fmt.Errorf("CreateUser - unable to generate hash for password: %w", err)
+	user := &domain.User{
+		Oid:       uuid.New(),
+		Nickname:  info.GetNickname(),
+		Email:     info.GetEmail(),
+		FirstName: info.GetFirstName(),
+		LastName:  info.GetLastName(),
+		State:     domain.Pending,
+	}
+	if err := validateUser(user); err != nil {
+		return &proto.CreateUserResponse{}, toStatus("CreateUser", err)
 	}
 
-	user.Oid.Value = uuid.New().String()
+	hash, err := hashPassword("password", req.GetPassword())
+	if err != nil {
+		log.Warnf("CreateUser: %s", err)
+		return &proto.CreateUserResponse{}, toStatus("CreateUser", err)
+	}
+	user.PasswordHash = hash
 
-	err = s.DB.CreateUser(user, string(hash), domain.Active)
+	err = s.DB.CreateUser(ctx, user)
 	if err != nil {
 		log.Warnf("CreateUser: %s", err)
-		return &proto.CreateUserResponse{}, fmt.Errorf("CreateUser2: %w", err)
+		return &proto.CreateUserResponse{}, toStatus("CreateUser", err)
+	}
+
+	// The user is created either way: a lost token can be sent again with
+	// ResendVerification.
+	if err := s.sendVerification(ctx, user.Oid, user.Email); err != nil {
+		log.Warnf("CreateUser: unable to send verification to user %s: %s", user.Oid, err)
 	}
 
 	log.Infof("Successfully created user %s", user.Nickname)
 	return &proto.CreateUserResponse{
-		Oid: &proto.UUID{Value: user.Oid.Value},
+		Oid: encode.UUID(user.Oid),
 	}, nil
 }
 
 func (s *ServerAPI) GetUserByEmail(ctx context.Context, req *proto.GetUserByEmailRequest) (*proto.GetUserByEmailResponse, error) {
-	user, err := s.DB.GetUserByEmail(req.GetEmail())
+	if err := checkIncludeDeleted(ctx, req.GetIncludeDeleted()); err != nil {
+		return &proto.GetUserByEmailResponse{}, toStatus("GetUserByEmail", err)
+	}
+
+	user, err := s.DB.GetUserByEmail(ctx, req.GetEmail(), req.GetIncludeDeleted())
 	if err != nil {
 		log.Warnf("GetUserByEmail: %s", err)
-		return &proto.GetUserByEmailResponse{}, fmt.Errorf("GetUserByEmail: %w", err)
+		return &proto.GetUserByEmailResponse{}, toStatus("GetUserByEmail", err)
 	}
 
 	return &proto.GetUserByEmailResponse{
-		User: user,
+		User: encode.User(user),
 	}, nil
 }
 
@@ -61,58 +112,133 @@ func (s *ServerAPI) GetUserByID(ctx context.Context, req *proto.GetUserByIDReque
 	oid, err := uuid.Parse(req.Oid.GetValue())
 	if err != nil {
 		log.Warnf("GetUserByID: unable to parse uuid:%s", err)
-		return &proto.GetUserByIDResponse{}, fmt.Errorf("GetUserByID: %w", err)
+		return &proto.GetUserByIDResponse{}, toStatus("GetUserByID", invalidArgument("oid", err))
 	}
-	user, err := s.DB.GetUserByID(oid)
+	if err := checkIncludeDeleted(ctx, req.GetIncludeDeleted()); err != nil {
+		return &proto.GetUserByIDResponse{}, toStatus("GetUserByID", err)
+	}
+
+	user, err := s.DB.GetUserByID(ctx, oid, req.GetIncludeDeleted())
 	if err != nil {
-		log.Warnf("GetUserByEmail: %s", err)
-		return &proto.GetUserByIDResponse{}, fmt.Errorf("GetUserByID: %w", err)
+		log.Warnf("GetUserByID: %s", err)
+		return &proto.GetUserByIDResponse{}, toStatus("GetUserByID", err)
 	}
 
 	return &proto.GetUserByIDResponse{
-		User: user,
+		User: encode.User(user),
 	}, nil
 }
-func (s *ServerAPI) GetUsers(ctx context.Context, req *emptypb.Empty) (*proto.GetUsersResponse, error) {
+func (s *ServerAPI) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
+	query, err := listUsersQuery(req)
+	if err != nil {
+		return &proto.ListUsersResponse{}, toStatus("ListUsers", err)
+	}
 
-	users, err := s.DB.GetUsers()
+	page, err := s.DB.ListUsers(ctx, query)
 	if err != nil {
-		log.Warnf("GetUsers:%s", err)
-		return &proto.GetUsersResponse{}, fmt.Errorf("GetUsers: %w", err)
+		log.Warnf("ListUsers:%s", err)
+		return &proto.ListUsersResponse{}, toStatus("ListUsers", err)
 	}
 
-	return &proto.GetUsersResponse{
-		Users: users,
+	return &proto.ListUsersResponse{
+		Users:         convert.Users(page.Users),
+		NextPageToken: page.NextPageToken,
 	}, nil
 }
+
+func (s *ServerAPI) StreamUsers(req *proto.StreamUsersRequest, stream proto.UserService_StreamUsersServer) error {
+	filter, err := userFilter(req.GetFilter())
+	if err != nil {
+		return toStatus("StreamUsers", err)
+	}
+
+	err = s.DB.StreamUsers(stream.Context(), filter, func(user *domain.User) error {
+		return stream.Send(encode.User(user))
+	})
+	if err != nil {
+		log.Warnf("StreamUsers:%s", err)
+		return toStatus("StreamUsers", err)
+	}
+
+	return nil
+}
+
 func (s *ServerAPI) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UpdateUserResponse, error) {
 
-	user := req.GetUser()
+	info := req.GetUser()
+	if info == nil {
+		return &proto.UpdateUserResponse{IsOk: false}, toStatus("UpdateUser", invalidArgument("user", errors.New("user is required")))
+	}
 
-	err := s.DB.UpdateUser(user)
+	oid, err := uuid.Parse(info.GetOid().GetValue())
+	if err != nil {
+		return &proto.UpdateUserResponse{IsOk: false}, toStatus("UpdateUser", invalidArgument("user.oid", err))
+	}
+	version, err := encode.ParseEtag(info.GetEtag())
+	if err != nil {
+		return &proto.UpdateUserResponse{IsOk: false}, toStatus("UpdateUser", invalidArgument("user.etag", err))
+	}
+	user := &domain.User{
+		Oid:       oid,
+		Nickname:  info.GetNickname(),
+		Email:     info.GetEmail(),
+		FirstName: info.GetFirstName(),
+		LastName:  info.GetLastName(),
+		Version:   version,
+	}
+
+	fields, err := updateFields(req.GetUpdateMask(), user)
+	if err != nil {
+		return &proto.UpdateUserResponse{IsOk: false}, toStatus("UpdateUser", err)
+	}
+
+	updated, err := s.DB.UpdateUser(ctx, user, fields)
 	if err != nil {
 		log.Warnf("UpdateUser:%s", err)
-		return &proto.UpdateUserResponse{IsOk: false}, fmt.Errorf("UpdateUser: %w", err)
+		return &proto.UpdateUserResponse{IsOk: false}, toStatus("UpdateUser", err)
 	}
 
-	return &proto.UpdateUserResponse{IsOk: true}, nil
+	log.Infof("User %s was updated by %s", updated.Oid, caller(ctx))
+	return &proto.UpdateUserResponse{IsOk: true, User: encode.User(updated)}, nil
 }
 func (s *ServerAPI) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.DeleteUserResponse, error) {
 	oid, err := uuid.Parse(req.Oid.GetValue())
 	if err != nil {
 		log.Warnf("DeleteUser: unable to parse uuid:%s", err)
-		return &proto.DeleteUserResponse{IsOk: false}, fmt.Errorf("DeleteUser: %w", err)
+		return &proto.DeleteUserResponse{IsOk: false}, toStatus("DeleteUser", invalidArgument("oid", err))
 	}
 
-	err = s.DB.DeleteUser(oid)
+	version, err := encode.ParseEtag(req.GetEtag())
+	if err != nil {
+		return &proto.DeleteUserResponse{IsOk: false}, toStatus("DeleteUser", invalidArgument("etag", err))
+	}
+
+	err = s.DB.DeleteUser(ctx, oid, version)
 	if err != nil {
 		log.Warnf("DeleteUser:%s", err)
-		return &proto.DeleteUserResponse{IsOk: false}, fmt.Errorf("DeleteUser: %w", err)
+		return &proto.DeleteUserResponse{IsOk: false}, toStatus("DeleteUser", err)
 	}
 
+	log.Infof("User %s was deleted by %s", oid, caller(ctx))
 	return &proto.DeleteUserResponse{IsOk: true}, nil
 }
 
+func (s *ServerAPI) RestoreUser(ctx context.Context, req *proto.RestoreUserRequest) (*proto.RestoreUserResponse, error) {
+	oid, err := uuid.Parse(req.GetOid().GetValue())
+	if err != nil {
+		return &proto.RestoreUserResponse{IsOk: false}, toStatus("RestoreUser", invalidArgument("oid", err))
+	}
+
+	user, err := s.DB.RestoreUser(ctx, oid)
+	if err != nil {
+		log.Warnf("RestoreUser: %s", err)
+		return &proto.RestoreUserResponse{IsOk: false}, toStatus("RestoreUser", err)
+	}
+
+	log.Infof("User %s was restored by %s", oid, caller(ctx))
+	return &proto.RestoreUserResponse{IsOk: true, User: encode.User(user)}, nil
+}
+
 func сheckPassword(psw string) error {
 
 	if len(psw) < 8 {
@@ -144,3 +270,91 @@ func сheckPassword(psw string) error {
 	}
 	return errors.New("wrong password format: password must contatin at least 1 upper case letter, 1 lower case letter, 1 number and 1 symbol")
 }
+
+func listUsersQuery(req *proto.ListUsersRequest) (domain.ListUsersQuery, error) {
+	query := domain.ListUsersQuery{
+		PageSize:  int(req.GetPageSize()),
+		PageToken: req.GetPageToken(),
+	}
+	if query.PageSize < 0 {
+		return query, invalidArgument("page_size", errors.New("page size must not be negative"))
+	}
+
+	orderBy := strings.Fields(req.GetOrderBy())
+	switch {
+	case len(orderBy) == 0:
+	case len(orderBy) == 1:
+		query.OrderBy = orderBy[0]
+	case len(orderBy) == 2 && strings.EqualFold(orderBy[1], "desc"):
+		query.OrderBy, query.Desc = orderBy[0], true
+	case len(orderBy) == 2 && strings.EqualFold(orderBy[1], "asc"):
+		query.OrderBy = orderBy[0]
+	default:
+		return query, invalidArgument("order_by", fmt.Errorf("malformed order %q", req.GetOrderBy()))
+	}
+
+	filter, err := userFilter(req.GetFilter())
+	if err != nil {
+		return query, err
+	}
+	query.Filter = filter
+
+	return query, nil
+}
+
+func userFilter(filter *proto.UserFilter) (domain.UserFilter, error) {
+	var f domain.UserFilter
+	for _, st := range filter.GetStates() {
+		state, err := encode.StateFromProto(st)
+		if err != nil {
+			return f, invalidArgument("filter.states", err)
+		}
+		f.States = append(f.States, state)
+	}
+	f.NicknamePrefix = filter.GetNicknamePrefix()
+	f.EmailPrefix = filter.GetEmailPrefix()
+	if filter.GetCreatedAfter() != nil {
+		f.CreatedAfter = filter.GetCreatedAfter().AsTime()
+	}
+	if filter.GetCreatedBefore() != nil {
+		f.CreatedBefore = filter.GetCreatedBefore().AsTime()
+	}
+	f.IncludeDeleted = filter.GetIncludeDeleted()
+	return f, nil
+}
+
+// updateFields validates mask against UserInfo and returns the fields to
+// update, checking their new values. An empty mask selects every field in
+// domain.UserFields.
+func updateFields(mask *fieldmaskpb.FieldMask, user *domain.User) ([]string, error) {
+	fields := domain.UserFields
+	if len(mask.GetPaths()) > 0 {
+		if !mask.IsValid(&proto.UserInfo{}) {
+			return nil, invalidArgument("update_mask", fmt.Errorf("unknown field in %v", mask.GetPaths()))
+		}
+		normalized := &fieldmaskpb.FieldMask{Paths: slices.Clone(mask.GetPaths())}
+		normalized.Normalize()
+		fields = normalized.GetPaths()
+	}
+
+	for _, field := range fields {
+		if !slices.Contains(domain.UserFields, field) {
+			return nil, invalidArgument("update_mask", fmt.Errorf("field %q can't be updated", field))
+		}
+	}
+	if err := validateUser(user, fields...); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// validateUser checks the listed fields of user, naming them as fields of
+// the user in the request.
+func validateUser(user *domain.User, fields ...string) error {
+	err := user.Validate(fields...)
+	var fieldErr *domain.FieldError
+	if errors.As(err, &fieldErr) {
+		return domain.NewFieldError(fieldErr.Kind, "user."+fieldErr.Field, fieldErr.Description)
+	}
+	return err
+}
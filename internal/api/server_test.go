@@ -5,19 +5,25 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
 	"github.com/sosshik/grpc-user-managment/internal/mocks"
 	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
 	"github.com/stretchr/testify/mock"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestServerAPI_CreateUser(t *testing.T) {
 	mockDB := mocks.NewDomainInterface(t)
-	s := ServerAPI{DB: mockDB}
+	s := ServerAPI{DB: mockDB, Notifier: &fakeNotifier{}, VerificationTokenTTL: time.Hour}
 	req1 := &proto.CreateUserRequest{
 		User: &proto.UserInfo{
 			Nickname:  "test",
@@ -48,6 +54,13 @@ func TestServerAPI_CreateUser(t *testing.T) {
 		},
 		Password: "Test123.",
 	}
+	req4 := &proto.CreateUserRequest{
+		User: &proto.UserInfo{
+			Nickname: "test",
+			Email:    "test.example.com",
+		},
+		Password: "Test123.",
+	}
 
 	type args struct {
 		ctx context.Context
@@ -65,12 +78,18 @@ func TestServerAPI_CreateUser(t *testing.T) {
 		{name: "Positive case", s: &s, args: args{ctx: context.Background(), req: req1}, needsMock: true, mockErr: nil, want: &proto.CreateUserResponse{}, wantErr: false},
 		{name: "Wrong Pass", s: &s, args: args{ctx: context.Background(), req: req2}, want: &proto.CreateUserResponse{}, wantErr: true},
 		{name: "DB Error", s: &s, args: args{ctx: context.Background(), req: req3}, needsMock: true, mockErr: errors.New("error"), want: &proto.CreateUserResponse{}, wantErr: true},
+		{name: "Malformed email", s: &s, args: args{ctx: context.Background(), req: req4}, want: &proto.CreateUserResponse{}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.needsMock {
-				mockDB.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(tt.mockErr).Once()
+				mockDB.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+					return u.Oid != uuid.Nil && u.State == domain.Pending && u.PasswordHash != ""
+				})).Return(tt.mockErr).Once()
+			}
+			if tt.needsMock && tt.mockErr == nil {
+				mockDB.On("CreateVerificationToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 			}
 
 			_, err := tt.s.CreateUser(tt.args.ctx, tt.args.req)
@@ -98,7 +117,7 @@ func TestServerAPI_GetUserByEmail(t *testing.T) {
 		name     string
 		s        *ServerAPI
 		args     args
-		mockResp *proto.UserInfo
+		mockResp *domain.User
 		mockErr  error
 		want     *proto.GetUserByEmailResponse
 		wantErr  bool
@@ -107,12 +126,14 @@ func TestServerAPI_GetUserByEmail(t *testing.T) {
 			name: "No Error",
 			s:    &s,
 			args: args{ctx: context.Background(), req: req},
-			mockResp: &proto.UserInfo{
-				Oid:       &proto.UUID{Value: oid.String()},
+			mockResp: &domain.User{
+				Oid:       oid,
 				Nickname:  "test",
 				Email:     "test@example.com",
 				FirstName: "test",
 				LastName:  "test",
+				State:     domain.Active,
+				Version:   1,
 			},
 			mockErr: nil,
 			want: &proto.GetUserByEmailResponse{
@@ -122,24 +143,25 @@ func TestServerAPI_GetUserByEmail(t *testing.T) {
 					Email:     "test@example.com",
 					FirstName: "test",
 					LastName:  "test",
+					Etag:      "1",
+					State:     proto.UserState_USER_STATE_ACTIVE,
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name:     "Error",
-			s:        &s,
-			args:     args{ctx: context.Background(), req: req},
-			mockResp: &proto.UserInfo{},
-			mockErr:  errors.New("error"),
-			want:     &proto.GetUserByEmailResponse{},
-			wantErr:  true,
+			name:    "Error",
+			s:       &s,
+			args:    args{ctx: context.Background(), req: req},
+			mockErr: errors.New("error"),
+			want:    &proto.GetUserByEmailResponse{},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			mockDB.On("GetUserByEmail", mock.Anything).Return(tt.mockResp, tt.mockErr).Once()
+			mockDB.On("GetUserByEmail", mock.Anything, mock.Anything, false).Return(tt.mockResp, tt.mockErr).Once()
 
 			got, err := tt.s.GetUserByEmail(tt.args.ctx, tt.args.req)
 			if (err != nil) != tt.wantErr {
@@ -170,7 +192,7 @@ func TestServerAPI_GetUserByID(t *testing.T) {
 		name     string
 		s        *ServerAPI
 		args     args
-		mockResp *proto.UserInfo
+		mockResp *domain.User
 		mockErr  error
 		want     *proto.GetUserByIDResponse
 		wantErr  bool
@@ -179,12 +201,14 @@ func TestServerAPI_GetUserByID(t *testing.T) {
 			name: "No Error",
 			s:    &s,
 			args: args{ctx: context.Background(), req: req},
-			mockResp: &proto.UserInfo{
-				Oid:       &proto.UUID{Value: oid.String()},
+			mockResp: &domain.User{
+				Oid:       oid,
 				Nickname:  "test",
 				Email:     "test@example.com",
 				FirstName: "test",
 				LastName:  "test",
+				State:     domain.Active,
+				Version:   1,
 			},
 			mockErr: nil,
 			want: &proto.GetUserByIDResponse{
@@ -194,24 +218,25 @@ func TestServerAPI_GetUserByID(t *testing.T) {
 					Email:     "test@example.com",
 					FirstName: "test",
 					LastName:  "test",
+					Etag:      "1",
+					State:     proto.UserState_USER_STATE_ACTIVE,
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name:     "Error",
-			s:        &s,
-			args:     args{ctx: context.Background(), req: req},
-			mockResp: &proto.UserInfo{},
-			mockErr:  errors.New("error"),
-			want:     &proto.GetUserByIDResponse{},
-			wantErr:  true,
+			name:    "Error",
+			s:       &s,
+			args:    args{ctx: context.Background(), req: req},
+			mockErr: errors.New("error"),
+			want:    &proto.GetUserByIDResponse{},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			mockDB.On("GetUserByID", mock.Anything).Return(tt.mockResp, tt.mockErr).Once()
+			mockDB.On("GetUserByID", mock.Anything, mock.Anything, false).Return(tt.mockResp, tt.mockErr).Once()
 
 			got, err := tt.s.GetUserByID(tt.args.ctx, tt.args.req)
 			if (err != nil) != tt.wantErr {
@@ -225,73 +250,109 @@ func TestServerAPI_GetUserByID(t *testing.T) {
 	}
 }
 
-func TestServerAPI_GetUsers(t *testing.T) {
+func TestServerAPI_ListUsers(t *testing.T) {
 
 	mockDB := mocks.NewDomainInterface(t)
 	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
 
 	type args struct {
 		ctx context.Context
-		req *emptypb.Empty
+		req *proto.ListUsersRequest
 	}
 	tests := []struct {
-		name     string
-		s        *ServerAPI
-		args     args
-		mockResp []*proto.UserInfo
-		mockErr  error
-		want     *proto.GetUsersResponse
-		wantErr  bool
+		name      string
+		s         *ServerAPI
+		args      args
+		needsMock bool
+		wantQuery domain.ListUsersQuery
+		mockResp  *domain.UserPage
+		mockErr   error
+		want      *proto.ListUsersResponse
+		wantErr   bool
 	}{
 		{
 			name: "Positive case",
 			s:    &s,
-			args: args{ctx: context.Background(), req: &emptypb.Empty{}},
-			mockResp: []*proto.UserInfo{
-				{
-					Oid:       &proto.UUID{Value: "sdgfadfgdfgas"},
-					Nickname:  "test",
-					Email:     "test@example.com",
-					FirstName: "test",
-					LastName:  "test",
+			args: args{ctx: context.Background(), req: &proto.ListUsersRequest{
+				PageSize:  10,
+				PageToken: "token",
+				OrderBy:   "nickname desc",
+				Filter: &proto.UserFilter{
+					States:         []proto.UserState{proto.UserState_USER_STATE_ACTIVE},
+					NicknamePrefix: "te",
 				},
+			}},
+			needsMock: true,
+			wantQuery: domain.ListUsersQuery{
+				Filter:    domain.UserFilter{States: []domain.State{domain.Active}, NicknamePrefix: "te"},
+				OrderBy:   "nickname",
+				Desc:      true,
+				PageSize:  10,
+				PageToken: "token",
+			},
+			mockResp: &domain.UserPage{
+				Users: []*domain.User{
+					{
+						Oid:       oid,
+						Nickname:  "test",
+						Email:     "test@example.com",
+						FirstName: "test",
+						LastName:  "test",
+						State:     domain.Active,
+						Version:   1,
+					},
+				},
+				NextPageToken: "next",
 			},
 			mockErr: nil,
-			want: &proto.GetUsersResponse{
+			want: &proto.ListUsersResponse{
 				Users: []*proto.UserInfo{
 					{
-						Oid:       &proto.UUID{Value: "sdgfadfgdfgas"},
+						Oid:       &proto.UUID{Value: oid.String()},
 						Nickname:  "test",
 						Email:     "test@example.com",
 						FirstName: "test",
 						LastName:  "test",
+						Etag:      "1",
+						State:     proto.UserState_USER_STATE_ACTIVE,
 					},
 				},
+				NextPageToken: "next",
 			},
 			wantErr: false,
 		},
 		{
-			name:     "Negative case",
-			s:        &s,
-			args:     args{ctx: context.Background(), req: &emptypb.Empty{}},
-			mockResp: []*proto.UserInfo{},
-			mockErr:  errors.New("error"),
-			want:     &proto.GetUsersResponse{},
-			wantErr:  true,
+			name:    "Malformed order",
+			s:       &s,
+			args:    args{ctx: context.Background(), req: &proto.ListUsersRequest{OrderBy: "nickname sideways"}},
+			want:    &proto.ListUsersResponse{},
+			wantErr: true,
+		},
+		{
+			name:      "Negative case",
+			s:         &s,
+			args:      args{ctx: context.Background(), req: &proto.ListUsersRequest{}},
+			needsMock: true,
+			wantQuery: domain.ListUsersQuery{},
+			mockErr:   errors.New("error"),
+			want:      &proto.ListUsersResponse{},
+			wantErr:   true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("ListUsers", mock.Anything, tt.wantQuery).Return(tt.mockResp, tt.mockErr).Once()
+			}
 
-			mockDB.On("GetUsers").Return(tt.mockResp, tt.mockErr).Once()
-
-			got, err := tt.s.GetUsers(tt.args.ctx, tt.args.req)
+			got, err := tt.s.ListUsers(tt.args.ctx, tt.args.req)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ServerAPI.GetUsers() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ServerAPI.ListUsers() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ServerAPI.GetUsers() = %v, want %v", got, tt.want)
+				t.Errorf("ServerAPI.ListUsers() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -304,23 +365,53 @@ func TestServerAPI_UpdateUser(t *testing.T) {
 
 	oid := "e93b6308-fbc2-40a7-90fc-84627f1580dd"
 
+	user := &proto.UserInfo{
+		Oid:       &proto.UUID{Value: oid},
+		Nickname:  "test",
+		Email:     "test@example.com",
+		FirstName: "test",
+		LastName:  "test",
+		Etag:      "1",
+	}
+	stored := &domain.User{
+		Oid:       uuid.MustParse(oid),
+		Nickname:  "test",
+		Email:     "test@example.com",
+		FirstName: "test",
+		LastName:  "test",
+		State:     domain.Active,
+		Version:   2,
+	}
+	storedInfo := &proto.UserInfo{
+		Oid:       &proto.UUID{Value: oid},
+		Nickname:  "test",
+		Email:     "test@example.com",
+		FirstName: "test",
+		LastName:  "test",
+		Etag:      "2",
+		State:     proto.UserState_USER_STATE_ACTIVE,
+	}
 	req1 := &proto.UpdateUserRequest{
-		User: &proto.UserInfo{
-			Oid:       &proto.UUID{Value: oid},
-			Nickname:  "test",
-			Email:     "test@example.com",
-			FirstName: "test",
-			LastName:  "test",
-		},
+		User: user,
 	}
 	req2 := &proto.UpdateUserRequest{
-		User: &proto.UserInfo{
-			Oid:       &proto.UUID{Value: oid},
-			Nickname:  "test",
-			Email:     "test@example.com",
-			FirstName: "test",
-			LastName:  "test",
-		},
+		User:       &proto.UserInfo{Oid: &proto.UUID{Value: oid}, LastName: "test"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"last_name"}},
+	}
+	req3 := &proto.UpdateUserRequest{
+		User:       &proto.UserInfo{Oid: &proto.UUID{Value: oid}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"oid"}},
+	}
+	req4 := &proto.UpdateUserRequest{
+		User:       &proto.UserInfo{Oid: &proto.UUID{Value: oid}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+	}
+	req5 := &proto.UpdateUserRequest{
+		User:       &proto.UserInfo{Oid: &proto.UUID{Value: oid}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+	}
+	req6 := &proto.UpdateUserRequest{
+		User: &proto.UserInfo{Oid: &proto.UUID{Value: oid}, Nickname: "test", Email: "test@example.com", Etag: "abc"},
 	}
 
 	type args struct {
@@ -328,32 +419,88 @@ func TestServerAPI_UpdateUser(t *testing.T) {
 		req *proto.UpdateUserRequest
 	}
 	tests := []struct {
-		name      string
-		s         *ServerAPI
-		args      args
-		needsMock bool
-		mockErr   error
-		want      *proto.UpdateUserResponse
-		wantErr   bool
+		name       string
+		s          *ServerAPI
+		args       args
+		needsMock  bool
+		wantFields []string
+		mockResp   *domain.User
+		mockErr    error
+		want       *proto.UpdateUserResponse
+		wantErr    bool
 	}{
 
 		{
-			name:      "Positive case",
-			s:         &s,
-			args:      args{ctx: context.Background(), req: req1},
-			needsMock: true,
-			mockErr:   nil,
+			name:       "Positive case",
+			s:          &s,
+			args:       args{ctx: context.Background(), req: req1},
+			needsMock:  true,
+			wantFields: []string{"nickname", "email", "first_name", "last_name"},
+			mockResp:   stored,
+			mockErr:    nil,
 			want: &proto.UpdateUserResponse{
 				IsOk: true,
+				User: storedInfo,
 			},
 			wantErr: false,
 		},
 		{
-			name:      "DB error case",
-			s:         &s,
-			args:      args{ctx: context.Background(), req: req2},
-			needsMock: true,
-			mockErr:   errors.New("error"),
+			name:       "Masked update",
+			s:          &s,
+			args:       args{ctx: context.Background(), req: req2},
+			needsMock:  true,
+			wantFields: []string{"last_name"},
+			mockResp:   stored,
+			mockErr:    nil,
+			want: &proto.UpdateUserResponse{
+				IsOk: true,
+				User: storedInfo,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Immutable field in mask",
+			s:    &s,
+			args: args{ctx: context.Background(), req: req3},
+			want: &proto.UpdateUserResponse{
+				IsOk: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown field in mask",
+			s:    &s,
+			args: args{ctx: context.Background(), req: req4},
+			want: &proto.UpdateUserResponse{
+				IsOk: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Empty email",
+			s:    &s,
+			args: args{ctx: context.Background(), req: req5},
+			want: &proto.UpdateUserResponse{
+				IsOk: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Malformed etag",
+			s:    &s,
+			args: args{ctx: context.Background(), req: req6},
+			want: &proto.UpdateUserResponse{
+				IsOk: false,
+			},
+			wantErr: true,
+		},
+		{
+			name:       "DB error case",
+			s:          &s,
+			args:       args{ctx: context.Background(), req: req1},
+			needsMock:  true,
+			wantFields: []string{"nickname", "email", "first_name", "last_name"},
+			mockErr:    errors.New("error"),
 			want: &proto.UpdateUserResponse{
 				IsOk: false,
 			},
@@ -364,7 +511,9 @@ func TestServerAPI_UpdateUser(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.needsMock {
-				mockDB.On("UpdateUser", mock.Anything).Return(tt.mockErr).Once()
+				mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+					return u.Oid == stored.Oid
+				}), tt.wantFields).Return(tt.mockResp, tt.mockErr).Once()
 			}
 
 			got, err := tt.s.UpdateUser(tt.args.ctx, tt.args.req)
@@ -445,7 +594,7 @@ func TestServerAPI_DeleteUser(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.needsMock {
-				mockDB.On("DeleteUser", mock.Anything).Return(tt.mockErr).Once()
+				mockDB.On("DeleteUser", mock.Anything, mock.Anything, mock.Anything).Return(tt.mockErr).Once()
 			}
 			got, err := tt.s.DeleteUser(tt.args.ctx, tt.args.req)
 			if (err != nil) != tt.wantErr {
@@ -459,3 +608,173 @@ func TestServerAPI_DeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestServerAPI_NotFound(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := "e93b6308-fbc2-40a7-90fc-84627f1580dd"
+	notFound := fmt.Errorf("unable to execute query to DB: %w", domain.ErrNotFound)
+
+	mockDB.On("GetUserByEmail", mock.Anything, mock.Anything, false).Return(nil, notFound).Once()
+	mockDB.On("GetUserByID", mock.Anything, mock.Anything, false).Return(nil, notFound).Once()
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFound).Once()
+	mockDB.On("DeleteUser", mock.Anything, mock.Anything, mock.Anything).Return(notFound).Once()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "GetUserByEmail", call: func() error {
+			_, err := s.GetUserByEmail(context.Background(), &proto.GetUserByEmailRequest{Email: "test@example.com"})
+			return err
+		}},
+		{name: "GetUserByID", call: func() error {
+			_, err := s.GetUserByID(context.Background(), &proto.GetUserByIDRequest{Oid: &proto.UUID{Value: oid}})
+			return err
+		}},
+		{name: "UpdateUser", call: func() error {
+			_, err := s.UpdateUser(context.Background(), &proto.UpdateUserRequest{User: &proto.UserInfo{Oid: &proto.UUID{Value: oid}, Nickname: "test", Email: "test@example.com"}})
+			return err
+		}},
+		{name: "DeleteUser", call: func() error {
+			_, err := s.DeleteUser(context.Background(), &proto.DeleteUserRequest{Oid: &proto.UUID{Value: oid}})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != codes.NotFound {
+				t.Errorf("ServerAPI.%s() code = %v, want %v", tt.name, code, codes.NotFound)
+			}
+		})
+	}
+}
+
+type fakeStreamUsersServer struct {
+	proto.UserService_StreamUsersServer
+	ctx  context.Context
+	sent []*proto.UserInfo
+}
+
+func (f *fakeStreamUsersServer) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeStreamUsersServer) Send(user *proto.UserInfo) error {
+	f.sent = append(f.sent, user)
+	return nil
+}
+
+func TestServerAPI_StreamUsers(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+
+	first, second := uuid.New(), uuid.New()
+	users := []*domain.User{
+		{Oid: first, Nickname: "first", State: domain.Active, Version: 1},
+		{Oid: second, Nickname: "second", State: domain.Pending, Version: 3},
+	}
+	sent := []*proto.UserInfo{
+		{Oid: &proto.UUID{Value: first.String()}, Nickname: "first", State: proto.UserState_USER_STATE_ACTIVE, Etag: "1"},
+		{Oid: &proto.UUID{Value: second.String()}, Nickname: "second", State: proto.UserState_USER_STATE_PENDING, Etag: "3"},
+	}
+
+	tests := []struct {
+		name      string
+		req       *proto.StreamUsersRequest
+		needsMock bool
+		mockErr   error
+		want      []*proto.UserInfo
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.StreamUsersRequest{}, needsMock: true, want: sent, wantCode: codes.OK},
+		{name: "Invalid state", req: &proto.StreamUsersRequest{Filter: &proto.UserFilter{States: []proto.UserState{42}}}, wantCode: codes.InvalidArgument},
+		{name: "Cancelled", req: &proto.StreamUsersRequest{}, needsMock: true, mockErr: context.Canceled, want: sent, wantCode: codes.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("StreamUsers", mock.Anything, domain.UserFilter{}, mock.Anything).Run(func(args mock.Arguments) {
+					send := args.Get(2).(func(*domain.User) error)
+					for _, u := range users {
+						_ = send(u)
+					}
+				}).Return(tt.mockErr).Once()
+			}
+
+			stream := &fakeStreamUsersServer{ctx: context.Background()}
+			err := s.StreamUsers(tt.req, stream)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.StreamUsers() code = %v, want %v", code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(stream.sent, tt.want) {
+				t.Errorf("ServerAPI.StreamUsers() sent = %v, want %v", stream.sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerAPI_GetUserByID_IncludeDeleted(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	req := &proto.GetUserByIDRequest{Oid: &proto.UUID{Value: oid.String()}, IncludeDeleted: true}
+
+	tests := []struct {
+		name      string
+		roles     []domain.Role
+		needsMock bool
+		wantCode  codes.Code
+	}{
+		{name: "Admin", roles: []domain.Role{domain.RoleAdmin}, needsMock: true, wantCode: codes.OK},
+		{name: "Support", roles: []domain.Role{domain.RoleSupport}, wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.NewContext(context.Background(), &auth.Principal{Oid: uuid.New(), Roles: tt.roles})
+			if tt.needsMock {
+				mockDB.On("GetUserByID", mock.Anything, oid, true).Return(&domain.User{Oid: oid, State: domain.Deleted}, nil).Once()
+			}
+
+			_, err := s.GetUserByID(ctx, req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.GetUserByID() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServerAPI_RestoreUser(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.RestoreUserRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.RestoreUserRequest{Oid: &proto.UUID{Value: oid.String()}}, needsMock: true, wantCode: codes.OK},
+		{name: "Not deleted", req: &proto.RestoreUserRequest{Oid: &proto.UUID{Value: oid.String()}}, needsMock: true, mockErr: fmt.Errorf("user is not deleted: %w", domain.ErrFailedPrecondition), wantCode: codes.FailedPrecondition},
+		{name: "Purged", req: &proto.RestoreUserRequest{Oid: &proto.UUID{Value: oid.String()}}, needsMock: true, mockErr: fmt.Errorf("user %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "Wrong UUID", req: &proto.RestoreUserRequest{Oid: &proto.UUID{Value: "abc"}}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				var user *domain.User
+				if tt.mockErr == nil {
+					user = &domain.User{Oid: oid, State: domain.Active}
+				}
+				mockDB.On("RestoreUser", mock.Anything, oid).Return(user, tt.mockErr).Once()
+			}
+
+			_, err := s.RestoreUser(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.RestoreUser() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+)
+
+func (s *ServerAPI) BanUser(ctx context.Context, req *proto.BanUserRequest) (*proto.BanUserResponse, error) {
+	user, err := s.changeState(ctx, "BanUser", req.GetOid(), domain.StateChange{State: domain.Banned, Reason: req.GetReason()})
+	if err != nil {
+		return &proto.BanUserResponse{IsOk: false}, err
+	}
+	return &proto.BanUserResponse{IsOk: true, User: encode.User(user)}, nil
+}
+
+func (s *ServerAPI) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest) (*proto.UnbanUserResponse, error) {
+	user, err := s.changeState(ctx, "UnbanUser", req.GetOid(), domain.StateChange{State: domain.Active, Reason: req.GetReason()})
+	if err != nil {
+		return &proto.UnbanUserResponse{IsOk: false}, err
+	}
+	return &proto.UnbanUserResponse{IsOk: true, User: encode.User(user)}, nil
+}
+
+func (s *ServerAPI) SuspendUser(ctx context.Context, req *proto.SuspendUserRequest) (*proto.SuspendUserResponse, error) {
+	if req.GetUntil() == nil {
+		return &proto.SuspendUserResponse{IsOk: false}, toStatus("SuspendUser", invalidArgument("until", errors.New("until is required")))
+	}
+	until := req.GetUntil().AsTime()
+	if !until.After(time.Now()) {
+		return &proto.SuspendUserResponse{IsOk: false}, toStatus("SuspendUser", invalidArgument("until", errors.New("until must be in the future")))
+	}
+
+	user, err := s.changeState(ctx, "SuspendUser", req.GetOid(), domain.StateChange{State: domain.Suspended, Reason: req.GetReason(), Until: until})
+	if err != nil {
+		return &proto.SuspendUserResponse{IsOk: false}, err
+	}
+	return &proto.SuspendUserResponse{IsOk: true, User: encode.User(user)}, nil
+}
+
+// changeState validates and applies a state change requested by method. The
+// returned error is a gRPC status.
+func (s *ServerAPI) changeState(ctx context.Context, method string, protoOid *proto.UUID, change domain.StateChange) (*domain.User, error) {
+	oid, err := uuid.Parse(protoOid.GetValue())
+	if err != nil {
+		return nil, toStatus(method, invalidArgument("oid", err))
+	}
+	if change.Reason == "" {
+		return nil, toStatus(method, invalidArgument("reason", errors.New("reason is required")))
+	}
+
+	user, err := s.DB.ChangeState(ctx, oid, change)
+	if err != nil {
+		log.Warnf("%s: %s", method, err)
+		return nil, toStatus(method, err)
+	}
+
+	log.Infof("User %s is %s now, changed by %s: %s", oid, change.State, caller(ctx), change.Reason)
+	return user, nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestServerAPI_BanUser(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	banned := &domain.User{Oid: oid, State: domain.Banned, StateReason: "spam"}
+
+	tests := []struct {
+		name      string
+		req       *proto.BanUserRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.BanUserRequest{Oid: &proto.UUID{Value: oid.String()}, Reason: "spam"}, needsMock: true, wantCode: codes.OK},
+		{name: "Already banned", req: &proto.BanUserRequest{Oid: &proto.UUID{Value: oid.String()}, Reason: "spam"}, needsMock: true, mockErr: domain.CheckTransition(domain.Banned, domain.Banned), wantCode: codes.FailedPrecondition},
+		{name: "Unknown user", req: &proto.BanUserRequest{Oid: &proto.UUID{Value: oid.String()}, Reason: "spam"}, needsMock: true, mockErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "No reason", req: &proto.BanUserRequest{Oid: &proto.UUID{Value: oid.String()}}, wantCode: codes.InvalidArgument},
+		{name: "Wrong UUID", req: &proto.BanUserRequest{Oid: &proto.UUID{Value: "abc"}, Reason: "spam"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				var user *domain.User
+				if tt.mockErr == nil {
+					user = banned
+				}
+				mockDB.On("ChangeState", mock.Anything, oid, domain.StateChange{State: domain.Banned, Reason: "spam"}).Return(user, tt.mockErr).Once()
+			}
+
+			got, err := s.BanUser(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.BanUser() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && got.GetUser().GetState() != proto.UserState_USER_STATE_BANNED {
+				t.Errorf("ServerAPI.BanUser() state = %v", got.GetUser().GetState())
+			}
+		})
+	}
+}
+
+func TestServerAPI_UnbanUser(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	mockDB.On("ChangeState", mock.Anything, oid, domain.StateChange{State: domain.Active, Reason: "appeal"}).
+		Return(nil, domain.CheckTransition(domain.Pending, domain.Active)).Once()
+	_, err := s.UnbanUser(context.Background(), &proto.UnbanUserRequest{Oid: &proto.UUID{Value: oid.String()}, Reason: "appeal"})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("ServerAPI.UnbanUser() code = %v, want %v", code, codes.FailedPrecondition)
+	}
+}
+
+func TestServerAPI_SuspendUser(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name      string
+		until     *timestamppb.Timestamp
+		needsMock bool
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", until: timestamppb.New(until), needsMock: true, wantCode: codes.OK},
+		{name: "No until", wantCode: codes.InvalidArgument},
+		{name: "Until in the past", until: timestamppb.New(time.Now().Add(-time.Hour)), wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("ChangeState", mock.Anything, oid, mock.MatchedBy(func(c domain.StateChange) bool {
+					return c.State == domain.Suspended && c.Until.Equal(until) && c.Reason == "abuse"
+				})).Return(&domain.User{Oid: oid, State: domain.Suspended, SuspendedUntil: until}, nil).Once()
+			}
+
+			_, err := s.SuspendUser(context.Background(), &proto.SuspendUserRequest{Oid: &proto.UUID{Value: oid.String()}, Reason: "abuse", Until: tt.until})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.SuspendUser() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
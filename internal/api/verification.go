@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/notify"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+)
+
+func (s *ServerAPI) VerifyEmail(ctx context.Context, req *proto.VerifyEmailRequest) (*proto.VerifyEmailResponse, error) {
+	if req.GetToken() == "" {
+		return &proto.VerifyEmailResponse{IsOk: false}, toStatus("VerifyEmail", invalidArgument("token", errors.New("token is required")))
+	}
+
+	oid, err := s.DB.VerifyEmail(ctx, token.Hash(req.GetToken()))
+	if err != nil {
+		log.Warnf("VerifyEmail: %s", err)
+		return &proto.VerifyEmailResponse{IsOk: false}, toStatus("VerifyEmail", err)
+	}
+
+	log.Infof("Email of user %s was verified", oid)
+	return &proto.VerifyEmailResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) ResendVerification(ctx context.Context, req *proto.ResendVerificationRequest) (*proto.ResendVerificationResponse, error) {
+	if req.GetEmail() == "" {
+		return &proto.ResendVerificationResponse{IsOk: false}, toStatus("ResendVerification", invalidArgument("email", errors.New("email is required")))
+	}
+
+	// Answered at once like RequestPasswordReset, so that neither the
+	// response nor its timing tells which emails are registered.
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		s.resendVerification(ctx, req.GetEmail())
+	}()
+	return &proto.ResendVerificationResponse{IsOk: true}, nil
+}
+
+// resendVerification sends a new verification token to the pending user
+// with the email. The caller got its response already, so failures are only
+// logged.
+func (s *ServerAPI) resendVerification(ctx context.Context, email string) {
+	creds, err := s.DB.GetCredentials(ctx, email, "")
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Infof("ResendVerification: unknown email")
+		return
+	}
+	if err != nil {
+		log.Warnf("ResendVerification: %s", err)
+		return
+	}
+	if creds.State != domain.Pending {
+		log.Infof("ResendVerification: user %s is in state %d", creds.Oid, creds.State)
+		return
+	}
+
+	if err := s.sendVerification(ctx, creds.Oid, email); err != nil {
+		log.Warnf("ResendVerification: user %s: %s", creds.Oid, err)
+	}
+}
+
+// sendVerification issues a new verification token for the user and sends
+// it to email. Tokens issued before stop working.
+func (s *ServerAPI) sendVerification(ctx context.Context, oid uuid.UUID, email string) error {
+	verification, hash, err := token.NewOpaque()
+	if err != nil {
+		return fmt.Errorf("unable to generate token: %w", err)
+	}
+	expires := time.Now().Add(s.VerificationTokenTTL)
+	if err := s.DB.CreateVerificationToken(ctx, oid, hash, expires); err != nil {
+		return err
+	}
+
+	err = s.Notifier.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "Email verification",
+		Body: fmt.Sprintf("Use this token to verify your email: %s\nIt expires at %s.",
+			verification, expires.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to send token: %w", err)
+	}
+	return nil
+}
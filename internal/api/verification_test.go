@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerAPI_VerifyEmail(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.VerifyEmailRequest
+		needsMock bool
+		mockErr   error
+		wantCode  codes.Code
+	}{
+		{name: "Positive case", req: &proto.VerifyEmailRequest{Token: "token"}, needsMock: true, wantCode: codes.OK},
+		{name: "Expired token", req: &proto.VerifyEmailRequest{Token: "token"}, needsMock: true, mockErr: domain.NewFieldError(domain.ErrInvalidArgument, "token", "token is invalid or expired"), wantCode: codes.InvalidArgument},
+		{name: "Already verified", req: &proto.VerifyEmailRequest{Token: "token"}, needsMock: true, mockErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.NotFound},
+		{name: "Empty token", req: &proto.VerifyEmailRequest{}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("VerifyEmail", mock.Anything, token.Hash(tt.req.GetToken())).Return(oid, tt.mockErr).Once()
+			}
+
+			_, err := s.VerifyEmail(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.VerifyEmail() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServerAPI_ResendVerification(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name        string
+		credsState  domain.State
+		credsErr    error
+		needsToken  bool
+		notifyErr   error
+		wantCode    codes.Code
+		wantMessage bool
+	}{
+		{name: "Positive case", credsState: domain.Pending, needsToken: true, wantCode: codes.OK, wantMessage: true},
+		{name: "Unknown email", credsErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantCode: codes.OK},
+		{name: "Already active", credsState: domain.Active, wantCode: codes.OK},
+		{name: "Database error", credsErr: errors.New("connection refused"), wantCode: codes.OK},
+		{name: "Notifier error", credsState: domain.Pending, needsToken: true, notifyErr: errors.New("smtp down"), wantCode: codes.OK, wantMessage: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &fakeNotifier{err: tt.notifyErr}
+			s := ServerAPI{DB: mockDB, Notifier: notifier, VerificationTokenTTL: time.Hour}
+
+			var creds *domain.Credentials
+			if tt.credsErr == nil {
+				creds = &domain.Credentials{Oid: oid, State: tt.credsState}
+			}
+			mockDB.On("GetCredentials", mock.Anything, "test@example.com", "").Return(creds, tt.credsErr).Once()
+			var storedHash string
+			if tt.needsToken {
+				mockDB.On("CreateVerificationToken", mock.Anything, oid, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					storedHash = args.String(2)
+				}).Return(nil).Once()
+			}
+
+			resp, err := s.ResendVerification(context.Background(), &proto.ResendVerificationRequest{Email: "test@example.com"})
+			if code := status.Code(err); code != tt.wantCode || !resp.GetIsOk() {
+				t.Fatalf("ServerAPI.ResendVerification() code = %v, ok = %v, want %v", code, resp.GetIsOk(), tt.wantCode)
+			}
+			s.Wait()
+			if (len(notifier.sent) == 1) != tt.wantMessage {
+				t.Fatalf("ServerAPI.ResendVerification() sent %d messages, want message %v", len(notifier.sent), tt.wantMessage)
+			}
+			if tt.wantMessage {
+				_, rest, _ := strings.Cut(notifier.sent[0].Body, "email: ")
+				sentToken, _, _ := strings.Cut(rest, "\n")
+				if token.Hash(sentToken) != storedHash {
+					t.Errorf("message body %q doesn't contain the stored token", notifier.sent[0].Body)
+				}
+			}
+		})
+	}
+}
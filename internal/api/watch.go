@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	watchBatchSize = 100
+	// defaultWatchPollInterval bounds the delay of events relayed by other
+	// instances, which the local broker does not see.
+	defaultWatchPollInterval = time.Second
+)
+
+func (s *ServerAPI) WatchUsers(req *proto.WatchUsersRequest, stream proto.UserService_WatchUsersServer) error {
+	after := req.GetAfterOffset()
+	if after < 0 {
+		return toStatus("WatchUsers", invalidArgument("after_offset", errors.New("offset must not be negative")))
+	}
+
+	// Subscribe before reading the outbox, so that no event published in
+	// between goes unnoticed. Events from the broker only wake the stream
+	// up, they are always read from the outbox to keep the offset order.
+	var wake <-chan *domain.UserEvent
+	if s.Events != nil {
+		sub := s.Events.Subscribe(1)
+		defer sub.Close()
+		wake = sub.C
+	}
+	interval := s.WatchPollInterval
+	if interval <= 0 {
+		interval = defaultWatchPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx := stream.Context()
+	checked := after == 0
+	for {
+		for {
+			events, err := s.DB.ListUserEvents(ctx, after, watchBatchSize)
+			if err != nil {
+				log.Warnf("WatchUsers: %s", err)
+				return toStatus("WatchUsers", err)
+			}
+			// Checked after the first read, so that events purged while it
+			// ran are noticed rather than skipped.
+			if !checked {
+				if err := s.checkRetained(ctx, after); err != nil {
+					return err
+				}
+				checked = true
+			}
+			for _, ev := range events {
+				if err := stream.Send(encode.UserEvent(ev)); err != nil {
+					return err
+				}
+				after = ev.Offset
+			}
+			if len(events) < watchBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return toStatus("WatchUsers", ctx.Err())
+		case <-s.Stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkRetained fails with OutOfRange if events following after were purged
+// from the outbox, so that a client that fell behind the retention period
+// doesn't miss them silently.
+func (s *ServerAPI) checkRetained(ctx context.Context, after int64) error {
+	first, err := s.DB.FirstUserEventOffset(ctx)
+	if err != nil {
+		log.Warnf("WatchUsers: %s", err)
+		return toStatus("WatchUsers", err)
+	}
+	if after+1 < first {
+		return status.Errorf(codes.OutOfRange, "events after offset %d are no longer retained, the oldest is %d", after, first)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeWatchUsersServer cancels the stream once it has sent want events.
+type fakeWatchUsersServer struct {
+	proto.UserService_WatchUsersServer
+	ctx    context.Context
+	cancel context.CancelFunc
+	want   int
+	sent   []*proto.UserEvent
+}
+
+func (f *fakeWatchUsersServer) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchUsersServer) Send(ev *proto.UserEvent) error {
+	f.sent = append(f.sent, ev)
+	if len(f.sent) == f.want {
+		f.cancel()
+	}
+	return nil
+}
+
+func newFakeWatchUsersServer(want int) *fakeWatchUsersServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &fakeWatchUsersServer{ctx: ctx, cancel: cancel, want: want}
+}
+
+func TestServerAPI_WatchUsers(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	broker := events.NewBroker()
+	s := ServerAPI{DB: mockDB, Events: broker, WatchPollInterval: time.Hour}
+
+	t.Run("Replay", func(t *testing.T) {
+		replayed := []*domain.UserEvent{{Offset: 1}, {Offset: 2}}
+		mockDB.On("ListUserEvents", mock.Anything, int64(0), watchBatchSize).Return(replayed, nil).Once()
+
+		stream := newFakeWatchUsersServer(2)
+		err := s.WatchUsers(&proto.WatchUsersRequest{}, stream)
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("ServerAPI.WatchUsers() code = %v, want %v", code, codes.Canceled)
+		}
+		if len(stream.sent) != 2 {
+			t.Errorf("ServerAPI.WatchUsers() sent %d events, want 2", len(stream.sent))
+		}
+	})
+
+	t.Run("Resume and wait", func(t *testing.T) {
+		live := &domain.UserEvent{Offset: 7, Type: domain.EventUpdated, User: &domain.User{Nickname: "john"}}
+		mockDB.On("ListUserEvents", mock.Anything, int64(5), watchBatchSize).Run(func(mock.Arguments) {
+			_ = broker.Publish(context.Background(), []*domain.UserEvent{live})
+		}).Return(nil, nil).Once()
+		mockDB.On("ListUserEvents", mock.Anything, int64(5), watchBatchSize).Return([]*domain.UserEvent{live}, nil).Once()
+		mockDB.On("FirstUserEventOffset", mock.Anything).Return(int64(3), nil).Once()
+
+		stream := newFakeWatchUsersServer(1)
+		err := s.WatchUsers(&proto.WatchUsersRequest{AfterOffset: 5}, stream)
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("ServerAPI.WatchUsers() code = %v, want %v", code, codes.Canceled)
+		}
+		if len(stream.sent) != 1 || stream.sent[0].Offset != 7 || stream.sent[0].GetUser().GetNickname() != "john" {
+			t.Errorf("ServerAPI.WatchUsers() sent %v, want offset 7", stream.sent)
+		}
+	})
+
+	t.Run("Resume from purged events", func(t *testing.T) {
+		mockDB.On("ListUserEvents", mock.Anything, int64(5), watchBatchSize).Return([]*domain.UserEvent{{Offset: 9}}, nil).Once()
+		mockDB.On("FirstUserEventOffset", mock.Anything).Return(int64(9), nil).Once()
+
+		stream := newFakeWatchUsersServer(1)
+		err := s.WatchUsers(&proto.WatchUsersRequest{AfterOffset: 5}, stream)
+		if code := status.Code(err); code != codes.OutOfRange {
+			t.Errorf("ServerAPI.WatchUsers() code = %v, want %v", code, codes.OutOfRange)
+		}
+		if len(stream.sent) != 0 {
+			t.Errorf("ServerAPI.WatchUsers() sent %d events, want 0", len(stream.sent))
+		}
+	})
+
+	t.Run("Server stopping", func(t *testing.T) {
+		stopping := make(chan struct{})
+		close(stopping)
+		s := ServerAPI{DB: mockDB, WatchPollInterval: time.Hour, Stopping: stopping}
+		mockDB.On("ListUserEvents", mock.Anything, int64(0), watchBatchSize).Return(nil, nil).Once()
+
+		err := s.WatchUsers(&proto.WatchUsersRequest{}, newFakeWatchUsersServer(1))
+		if code := status.Code(err); code != codes.Unavailable {
+			t.Errorf("ServerAPI.WatchUsers() code = %v, want %v", code, codes.Unavailable)
+		}
+	})
+
+	t.Run("Negative offset", func(t *testing.T) {
+		err := s.WatchUsers(&proto.WatchUsersRequest{AfterOffset: -1}, newFakeWatchUsersServer(1))
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Errorf("ServerAPI.WatchUsers() code = %v, want %v", code, codes.InvalidArgument)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/api/convert"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+)
+
+const minWebhookSecretLen = 16
+
+func (s *ServerAPI) CreateWebhook(ctx context.Context, req *proto.CreateWebhookRequest) (*proto.CreateWebhookResponse, error) {
+	if err := checkWebhookURL(req.GetUrl()); err != nil {
+		return &proto.CreateWebhookResponse{}, toStatus("CreateWebhook", invalidArgument("url", err))
+	}
+	var eventTypes []domain.EventType
+	for _, t := range req.GetEventTypes() {
+		eventType, err := encode.EventTypeFromProto(t)
+		if err != nil {
+			return &proto.CreateWebhookResponse{}, toStatus("CreateWebhook", invalidArgument("event_types", err))
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	secret := req.GetSecret()
+	switch {
+	case secret == "":
+		var err error
+		secret, _, err = token.NewOpaque()
+		if err != nil {
+			log.Warnf("CreateWebhook: %s", err)
+			return &proto.CreateWebhookResponse{}, toStatus("CreateWebhook", err)
+		}
+	case len(secret) < minWebhookSecretLen:
+		return &proto.CreateWebhookResponse{}, toStatus("CreateWebhook", invalidArgument("secret", fmt.Errorf("secret must be at least %d characters", minWebhookSecretLen)))
+	}
+
+	webhook := &domain.Webhook{
+		ID:         uuid.New(),
+		URL:        req.GetUrl(),
+		EventTypes: eventTypes,
+	}
+	if err := s.DB.CreateWebhook(ctx, webhook, secret); err != nil {
+		log.Warnf("CreateWebhook: %s", err)
+		return &proto.CreateWebhookResponse{}, toStatus("CreateWebhook", err)
+	}
+
+	log.Infof("Webhook %s to %s was created by %s", webhook.ID, webhook.URL, caller(ctx))
+	return &proto.CreateWebhookResponse{Webhook: convert.Webhook(webhook), Secret: secret}, nil
+}
+
+func (s *ServerAPI) ListWebhooks(ctx context.Context, req *proto.ListWebhooksRequest) (*proto.ListWebhooksResponse, error) {
+	webhooks, err := s.DB.ListWebhooks(ctx)
+	if err != nil {
+		log.Warnf("ListWebhooks: %s", err)
+		return &proto.ListWebhooksResponse{}, toStatus("ListWebhooks", err)
+	}
+	resp := &proto.ListWebhooksResponse{}
+	for _, webhook := range webhooks {
+		resp.Webhooks = append(resp.Webhooks, convert.Webhook(webhook))
+	}
+	return resp, nil
+}
+
+func (s *ServerAPI) DeleteWebhook(ctx context.Context, req *proto.DeleteWebhookRequest) (*proto.DeleteWebhookResponse, error) {
+	id, err := uuid.Parse(req.GetId().GetValue())
+	if err != nil {
+		return &proto.DeleteWebhookResponse{IsOk: false}, toStatus("DeleteWebhook", invalidArgument("id", err))
+	}
+
+	if err := s.DB.DeleteWebhook(ctx, id); err != nil {
+		log.Warnf("DeleteWebhook: %s", err)
+		return &proto.DeleteWebhookResponse{IsOk: false}, toStatus("DeleteWebhook", err)
+	}
+
+	log.Infof("Webhook %s was deleted by %s", id, caller(ctx))
+	return &proto.DeleteWebhookResponse{IsOk: true}, nil
+}
+
+func (s *ServerAPI) ListWebhookDeliveries(ctx context.Context, req *proto.ListWebhookDeliveriesRequest) (*proto.ListWebhookDeliveriesResponse, error) {
+	query, err := listDeliveriesQuery(req)
+	if err != nil {
+		return &proto.ListWebhookDeliveriesResponse{}, toStatus("ListWebhookDeliveries", err)
+	}
+
+	page, err := s.DB.ListWebhookDeliveries(ctx, query)
+	if err != nil {
+		log.Warnf("ListWebhookDeliveries: %s", err)
+		return &proto.ListWebhookDeliveriesResponse{}, toStatus("ListWebhookDeliveries", err)
+	}
+
+	resp := &proto.ListWebhookDeliveriesResponse{NextPageToken: page.NextPageToken}
+	for _, d := range page.Deliveries {
+		delivery, err := convert.WebhookDelivery(d)
+		if err != nil {
+			log.Warnf("ListWebhookDeliveries: %s", err)
+			return &proto.ListWebhookDeliveriesResponse{}, toStatus("ListWebhookDeliveries", err)
+		}
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+	return resp, nil
+}
+
+func listDeliveriesQuery(req *proto.ListWebhookDeliveriesRequest) (domain.ListDeliveriesQuery, error) {
+	query := domain.ListDeliveriesQuery{
+		PageSize:  int(req.GetPageSize()),
+		PageToken: req.GetPageToken(),
+	}
+	if query.PageSize < 0 {
+		return query, invalidArgument("page_size", errors.New("page size must not be negative"))
+	}
+
+	if req.GetWebhookId().GetValue() != "" {
+		id, err := uuid.Parse(req.GetWebhookId().GetValue())
+		if err != nil {
+			return query, invalidArgument("webhook_id", err)
+		}
+		query.Filter.WebhookID = id
+	}
+	for _, st := range req.GetStates() {
+		state, err := convert.DeliveryStateFromProto(st)
+		if err != nil {
+			return query, invalidArgument("states", err)
+		}
+		query.Filter.States = append(query.Filter.States, state)
+	}
+	return query, nil
+}
+
+// checkWebhookURL accepts absolute http and https URLs.
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must use http or https")
+	}
+	if u.Host == "" {
+		return errors.New("url must have a host")
+	}
+	return nil
+}
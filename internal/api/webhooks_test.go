@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerAPI_CreateWebhook(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+
+	tests := []struct {
+		name       string
+		req        *proto.CreateWebhookRequest
+		needsMock  bool
+		wantSecret string
+		wantCode   codes.Code
+	}{
+		{
+			name:       "Positive case",
+			req:        &proto.CreateWebhookRequest{Url: "https://example.com/hook", EventTypes: []proto.UserEventType{proto.UserEventType_USER_EVENT_TYPE_CREATED}, Secret: "0123456789abcdef"},
+			needsMock:  true,
+			wantSecret: "0123456789abcdef",
+			wantCode:   codes.OK,
+		},
+		{name: "Generated secret", req: &proto.CreateWebhookRequest{Url: "http://localhost:9000/hook"}, needsMock: true, wantCode: codes.OK},
+		{name: "Relative URL", req: &proto.CreateWebhookRequest{Url: "/hook"}, wantCode: codes.InvalidArgument},
+		{name: "Unsupported scheme", req: &proto.CreateWebhookRequest{Url: "ftp://example.com/hook"}, wantCode: codes.InvalidArgument},
+		{name: "Short secret", req: &proto.CreateWebhookRequest{Url: "https://example.com/hook", Secret: "short"}, wantCode: codes.InvalidArgument},
+		{name: "Unspecified event type", req: &proto.CreateWebhookRequest{Url: "https://example.com/hook", EventTypes: []proto.UserEventType{0}}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsMock {
+				mockDB.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(w *domain.Webhook) bool {
+					return w.URL == tt.req.Url && w.ID != uuid.Nil && len(w.EventTypes) == len(tt.req.EventTypes)
+				}), mock.Anything).Return(nil).Once()
+			}
+
+			got, err := s.CreateWebhook(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ServerAPI.CreateWebhook() code = %v, want %v", code, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantSecret != "" && got.Secret != tt.wantSecret {
+				t.Errorf("ServerAPI.CreateWebhook() secret = %q, want %q", got.Secret, tt.wantSecret)
+			}
+			if len(got.Secret) < minWebhookSecretLen {
+				t.Errorf("ServerAPI.CreateWebhook() secret %q is too short", got.Secret)
+			}
+		})
+	}
+}
+
+func TestServerAPI_ListWebhookDeliveries(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	s := ServerAPI{DB: mockDB}
+	id := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+
+	tests := []struct {
+		name      string
+		req       *proto.ListWebhookDeliveriesRequest
+		wantQuery *domain.ListDeliveriesQuery
+		wantCode  codes.Code
+	}{
+		{
+			name: "Positive case",
+			req: &proto.ListWebhookDeliveriesRequest{
+				WebhookId: &proto.UUID{Value: id.String()},
+				States:    []proto.WebhookDeliveryState{proto.WebhookDeliveryState_WEBHOOK_DELIVERY_STATE_DEAD_LETTER},
+				PageSize:  20,
+			},
+			wantQuery: &domain.ListDeliveriesQuery{
+				Filter:   domain.DeliveryFilter{WebhookID: id, States: []domain.DeliveryState{domain.DeliveryDeadLetter}},
+				PageSize: 20,
+			},
+			wantCode: codes.OK,
+		},
+		{name: "Malformed webhook id", req: &proto.ListWebhookDeliveriesRequest{WebhookId: &proto.UUID{Value: "abc"}}, wantCode: codes.InvalidArgument},
+		{name: "Unknown state", req: &proto.ListWebhookDeliveriesRequest{States: []proto.WebhookDeliveryState{42}}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantQuery != nil {
+				mockDB.On("ListWebhookDeliveries", mock.Anything, *tt.wantQuery).Return(&domain.DeliveryPage{}, nil).Once()
+			}
+
+			_, err := s.ListWebhookDeliveries(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("ServerAPI.ListWebhookDeliveries() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
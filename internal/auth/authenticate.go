@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator identifies the caller of every RPC and stores it in the
+// context as a Principal. Users are identified by the access token in the
+// "authorization: Bearer <token>" metadata, services by a verified TLS client
+// certificate. A token takes precedence over the certificate, so a service
+// can act on behalf of a user.
+//
+// Calls without credentials pass through without a principal and invalid
+// credentials are only rejected by the Authorizer, so that public methods
+// such as Refresh keep working with an expired token attached.
+type Authenticator struct {
+	Tokens *token.Manager
+	DB     domain.DomainInterface
+	// Services maps client certificate common names to the roles of the
+	// service. Certificates of other services are rejected.
+	Services map[string][]domain.Role
+}
+
+func (a *Authenticator) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns ctx with the principal or, if the credentials are
+// invalid, the reason. Only failures to look the caller up are returned as
+// errors.
+func (a *Authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	var p *Principal
+	var err error
+	if bearer, ok := bearerToken(ctx); ok {
+		p, err = a.userPrincipal(ctx, bearer)
+	} else if cert, ok := clientCertificate(ctx); ok {
+		p, err = a.servicePrincipal(cert)
+	} else {
+		return ctx, nil
+	}
+
+	switch {
+	case err == nil:
+		return NewContext(ctx, p), nil
+	case status.Code(err) == codes.Unauthenticated:
+		log.Infof("rejected credentials: %s", err)
+		return context.WithValue(ctx, authErrorKey{}, err), nil
+	default:
+		return nil, err
+	}
+}
+
+func (a *Authenticator) userPrincipal(ctx context.Context, bearer string) (*Principal, error) {
+	claims, err := a.Tokens.VerifyAccessToken(bearer)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "access token is invalid: %s", err)
+	}
+	oid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "access token is invalid: %s", err)
+	}
+
+	// The token outlives a ban, a suspension or a deletion, so the state is
+	// checked on every call rather than only when the token is issued.
+	creds, err := a.DB.GetCredentialsByID(ctx, oid)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "user no longer exists")
+	}
+	if err != nil {
+		log.Warnf("unable to get credentials of user %s: %s", oid, err)
+		return nil, dbError(err)
+	}
+	if !creds.CanLogIn(time.Now()) {
+		return nil, status.Errorf(codes.Unauthenticated, "account is %s", creds.State)
+	}
+
+	roles, err := a.DB.GetRoles(ctx, oid)
+	if err != nil {
+		log.Warnf("unable to get roles of user %s: %s", oid, err)
+		return nil, dbError(err)
+	}
+	return &Principal{Oid: oid, Roles: roles}, nil
+}
+
+func dbError(err error) error {
+	if errors.Is(err, domain.ErrUnavailable) {
+		return status.Error(codes.Unavailable, "service is temporarily unavailable")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (a *Authenticator) servicePrincipal(cert *x509.Certificate) (*Principal, error) {
+	name := cert.Subject.CommonName
+	roles, ok := a.Services[name]
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "client certificate %q is not allowed", name)
+	}
+	return &Principal{Service: name, Roles: roles}, nil
+}
+
+// bearerToken returns the token of an "authorization: Bearer <token>"
+// metadata entry.
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(v, " ")
+		if ok && strings.EqualFold(scheme, "bearer") && token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// clientCertificate returns the client certificate of a TLS connection if
+// it was verified against the client CAs.
+func clientCertificate(ctx context.Context) (*x509.Certificate, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return tlsInfo.State.VerifiedChains[0][0], true
+}
+
+// ParseServiceRoles parses a list of client certificate common names and
+// their roles, such as "billing=support,backoffice=admin+support".
+func ParseServiceRoles(s string) (map[string][]domain.Role, error) {
+	services := make(map[string][]domain.Role)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, roleList, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("malformed service %q, expected name=role", entry)
+		}
+		for _, r := range strings.Split(roleList, "+") {
+			role, err := ParseRole(r)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+			services[name] = append(services[name], role)
+		}
+	}
+	return services, nil
+}
+
+// ParseRole parses the lower case name of a role.
+func ParseRole(s string) (domain.Role, error) {
+	switch s {
+	case "user":
+		return domain.RoleUser, nil
+	case "support":
+		return domain.RoleSupport, nil
+	case "admin":
+		return domain.RoleAdmin, nil
+	}
+	return 0, fmt.Errorf("unknown role %q", s)
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
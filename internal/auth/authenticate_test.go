@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func withClientCertificate(ctx context.Context, commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+}
+
+func TestAuthenticator_UnaryInterceptor(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	tokens := newTestTokens(t)
+	a := &Authenticator{
+		Tokens:   tokens,
+		DB:       mockDB,
+		Services: map[string][]domain.Role{"billing": {domain.RoleSupport}},
+	}
+
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	access, _, err := tokens.IssueAccessToken(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := func(ctx context.Context) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+access))
+	}
+
+	banned, suspended := domain.Banned, domain.Suspended
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		state         *domain.State
+		credsErr      error
+		rolesErr      error
+		needsCreds    bool
+		needsRoles    bool
+		want          *Principal
+		wantAuthError bool
+		wantCode      codes.Code
+	}{
+		{name: "No credentials", ctx: context.Background(), wantCode: codes.OK},
+		{name: "Access token", ctx: bearer(context.Background()), needsCreds: true, needsRoles: true, want: &Principal{Oid: oid, Roles: []domain.Role{domain.RoleUser}}, wantCode: codes.OK},
+		{name: "Token over certificate", ctx: bearer(withClientCertificate(context.Background(), "billing")), needsCreds: true, needsRoles: true, want: &Principal{Oid: oid, Roles: []domain.Role{domain.RoleUser}}, wantCode: codes.OK},
+		{name: "Client certificate", ctx: withClientCertificate(context.Background(), "billing"), want: &Principal{Service: "billing", Roles: []domain.Role{domain.RoleSupport}}, wantCode: codes.OK},
+		{name: "Unknown service", ctx: withClientCertificate(context.Background(), "unknown"), wantAuthError: true, wantCode: codes.OK},
+		{name: "Banned user", ctx: bearer(context.Background()), needsCreds: true, state: &banned, wantAuthError: true, wantCode: codes.OK},
+		{name: "Suspended user", ctx: bearer(context.Background()), needsCreds: true, state: &suspended, wantAuthError: true, wantCode: codes.OK},
+		{name: "Deleted user", ctx: bearer(context.Background()), needsCreds: true, credsErr: fmt.Errorf("query: %w", domain.ErrNotFound), wantAuthError: true, wantCode: codes.OK},
+		{name: "Credentials unavailable", ctx: bearer(context.Background()), needsCreds: true, credsErr: fmt.Errorf("query: %w", domain.ErrUnavailable), wantCode: codes.Unavailable},
+		{name: "Roles unavailable", ctx: bearer(context.Background()), needsCreds: true, needsRoles: true, rolesErr: fmt.Errorf("query: %w", domain.ErrUnavailable), wantCode: codes.Unavailable},
+		{name: "Roles error", ctx: bearer(context.Background()), needsCreds: true, needsRoles: true, rolesErr: errors.New("error"), wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsCreds {
+				var creds *domain.Credentials
+				if tt.credsErr == nil {
+					creds = &domain.Credentials{Oid: oid, State: domain.Active, SuspendedUntil: time.Now().Add(time.Hour)}
+					if tt.state != nil {
+						creds.State = *tt.state
+					}
+				}
+				mockDB.On("GetCredentialsByID", mock.Anything, oid).Return(creds, tt.credsErr).Once()
+			}
+			if tt.needsRoles {
+				mockDB.On("GetRoles", mock.Anything, oid).Return([]domain.Role{domain.RoleUser}, tt.rolesErr).Once()
+			}
+
+			var got *Principal
+			var gotAuthErr error
+			_, err := a.UnaryInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				got, _ = FromContext(ctx)
+				gotAuthErr = authError(ctx)
+				return nil, nil
+			})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Authenticator.UnaryInterceptor() code = %v, want %v", code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticator.UnaryInterceptor() principal = %v, want %v", got, tt.want)
+			}
+			if (gotAuthErr != nil) != tt.wantAuthError {
+				t.Errorf("Authenticator.UnaryInterceptor() auth error = %v, want %v", gotAuthErr, tt.wantAuthError)
+			}
+		})
+	}
+}
+
+func TestParseServiceRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string][]domain.Role
+		wantErr bool
+	}{
+		{name: "Empty", s: "", want: map[string][]domain.Role{}},
+		{name: "Several services", s: "billing=support, backoffice=admin+support", want: map[string][]domain.Role{
+			"billing":    {domain.RoleSupport},
+			"backoffice": {domain.RoleAdmin, domain.RoleSupport},
+		}},
+		{name: "Unknown role", s: "billing=root", wantErr: true},
+		{name: "No role", s: "billing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseServiceRoles(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseServiceRoles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseServiceRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer enforces the permissions of each method before calling its
+// handler. It relies on the principal stored by the Authenticator, which has
+// to run before it in the interceptor chain.
+type Authorizer struct{}
+
+func (a *Authorizer) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := authorize(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authorizes streaming calls. Their request is not known
+// yet, so only roles are checked.
+func (a *Authorizer) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := authorize(ss.Context(), info.FullMethod, nil); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func authorize(ctx context.Context, method string, req any) error {
+	r := ruleFor(method)
+	if r.public {
+		return nil
+	}
+
+	p, ok := FromContext(ctx)
+	if !ok {
+		if err := authError(ctx); err != nil {
+			return err
+		}
+		return status.Error(codes.Unauthenticated, "access token is required")
+	}
+	if !r.allows(p, req) {
+		log.Infof("%s: permission denied for %s", method, p)
+		return status.Errorf(codes.PermissionDenied, "%s: permission denied", method)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/sosshik/grpc-user-managment/internal/token"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestTokens(t *testing.T) *token.Manager {
+	key, err := token.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.NewManager(token.Config{Issuer: "test", AccessTTL: time.Minute, SigningKey: key})
+}
+
+// chain calls the Authenticator and Authorizer like the server does.
+func chain(a *Authenticator, ctx context.Context, method string, req any, handler grpc.UnaryHandler) (any, error) {
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	return a.UnaryInterceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		return (&Authorizer{}).UnaryInterceptor(ctx, req, info, handler)
+	})
+}
+
+func TestAuthorizer_UnaryInterceptor(t *testing.T) {
+	mockDB := mocks.NewDomainInterface(t)
+	tokens := newTestTokens(t)
+	a := &Authenticator{Tokens: tokens, DB: mockDB}
+
+	caller := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	other := uuid.MustParse("0b7e9f3c-3c1d-4f7a-9a57-0d2f3f1b6a11")
+	access, _, err := tokens.IssueAccessToken(caller)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getUser := func(oid uuid.UUID) *proto.GetUserByIDRequest {
+		return &proto.GetUserByIDRequest{Oid: &proto.UUID{Value: oid.String()}}
+	}
+
+	banned := domain.Banned
+	tests := []struct {
+		name     string
+		method   string
+		req      any
+		auth     string
+		state    *domain.State
+		roles    []domain.Role
+		wantCode codes.Code
+	}{
+		{name: "Public method", method: service + "Login", req: &proto.LoginRequest{}, wantCode: codes.OK},
+		{name: "Public method with invalid token", method: service + "Refresh", req: &proto.RefreshRequest{}, auth: "Bearer nonsense", wantCode: codes.OK},
+		{name: "Health check", method: "/grpc.health.v1.Health/Check", req: &proto.UUID{}, wantCode: codes.OK},
+		{name: "No token", method: service + "GetUserByID", req: getUser(caller), wantCode: codes.Unauthenticated},
+		{name: "Malformed token", method: service + "GetUserByID", req: getUser(caller), auth: "Bearer nonsense", wantCode: codes.Unauthenticated},
+		{name: "Self", method: service + "GetUserByID", req: getUser(caller), auth: "Bearer " + access, roles: []domain.Role{domain.RoleUser}, wantCode: codes.OK},
+		{name: "Banned self", method: service + "GetUserByID", req: getUser(caller), auth: "Bearer " + access, state: &banned, wantCode: codes.Unauthenticated},
+		{name: "Self without user role", method: service + "GetUserByID", req: getUser(caller), auth: "Bearer " + access, wantCode: codes.PermissionDenied},
+		{name: "Other user", method: service + "GetUserByID", req: getUser(other), auth: "Bearer " + access, roles: []domain.Role{domain.RoleUser}, wantCode: codes.PermissionDenied},
+		{name: "Support reads other user", method: service + "GetUserByID", req: getUser(other), auth: "bearer " + access, roles: []domain.Role{domain.RoleUser, domain.RoleSupport}, wantCode: codes.OK},
+		{name: "Support deletes user", method: service + "DeleteUser", req: &proto.DeleteUserRequest{Oid: &proto.UUID{Value: other.String()}}, auth: "Bearer " + access, roles: []domain.Role{domain.RoleSupport}, wantCode: codes.PermissionDenied},
+		{name: "Admin deletes user", method: service + "DeleteUser", req: &proto.DeleteUserRequest{Oid: &proto.UUID{Value: other.String()}}, auth: "Bearer " + access, roles: []domain.Role{domain.RoleAdmin}, wantCode: codes.OK},
+		{name: "User deletes self", method: service + "DeleteUser", req: &proto.DeleteUserRequest{Oid: &proto.UUID{Value: caller.String()}}, auth: "Bearer " + access, roles: []domain.Role{domain.RoleUser}, wantCode: codes.PermissionDenied},
+		{name: "Unknown method", method: "/proto.UserService/Unknown", req: &proto.UUID{}, auth: "Bearer " + access, roles: []domain.Role{domain.RoleSupport}, wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.auth != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.auth))
+			}
+			if tt.auth != "" && tt.auth != "Bearer nonsense" {
+				creds := &domain.Credentials{Oid: caller, State: domain.Active}
+				if tt.state != nil {
+					creds.State = *tt.state
+				}
+				mockDB.On("GetCredentialsByID", mock.Anything, caller).Return(creds, nil).Once()
+			}
+			if tt.auth != "" && tt.auth != "Bearer nonsense" && tt.state == nil {
+				mockDB.On("GetRoles", mock.Anything, caller).Return(tt.roles, nil).Once()
+			}
+
+			_, err := chain(a, ctx, tt.method, tt.req, func(ctx context.Context, req any) (any, error) {
+				return req, nil
+			})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Authorizer.UnaryInterceptor() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
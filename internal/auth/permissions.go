@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// rule describes who may call a method.
+type rule struct {
+	// public methods can be called without credentials.
+	public bool
+	// role is the least role allowed to call the method on any user.
+	role domain.Role
+	// target returns the oid of the user the request acts on. If set,
+	// holders of RoleUser may call the method on themselves.
+	target func(req any) string
+}
+
+const service = "/proto.UserService/"
+
+// permissions lists the rule of every method. Methods missing from it are
+// admin only.
+var permissions = map[string]rule{
+	// Health checks come from probes without credentials. Reflection is
+	// only registered when enabled in the config.
+	healthpb.Health_Check_FullMethodName:                                     {public: true},
+	healthpb.Health_Watch_FullMethodName:                                     {public: true},
+	reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName:        {public: true},
+	reflectionv1alphapb.ServerReflection_ServerReflectionInfo_FullMethodName: {public: true},
+
+	service + "CreateUser":            {public: true},
+	service + "Login":                 {public: true},
+	service + "Refresh":               {public: true},
+	service + "Revoke":                {public: true},
+	service + "GetPublicKeys":         {public: true},
+	service + "RequestPasswordReset":  {public: true},
+	service + "CompletePasswordReset": {public: true},
+	service + "VerifyEmail":           {public: true},
+	service + "ResendVerification":    {public: true},
+
+	service + "GetUserByID": {role: domain.RoleSupport, target: func(req any) string {
+		return req.(*proto.GetUserByIDRequest).GetOid().GetValue()
+	}},
+	service + "GetUserByEmail": {role: domain.RoleSupport},
+	service + "GetRoles": {role: domain.RoleSupport, target: func(req any) string {
+		return req.(*proto.GetRolesRequest).GetOid().GetValue()
+	}},
+	service + "UpdateUser": {role: domain.RoleAdmin, target: func(req any) string {
+		return req.(*proto.UpdateUserRequest).GetUser().GetOid().GetValue()
+	}},
+	service + "ChangePassword": {role: domain.RoleAdmin, target: func(req any) string {
+		return req.(*proto.ChangePasswordRequest).GetOid().GetValue()
+	}},
+
+	service + "SuspendUser": {role: domain.RoleSupport},
+	service + "WatchUsers":  {role: domain.RoleSupport},
+
+	service + "ListUsers":   {role: domain.RoleAdmin},
+	service + "StreamUsers": {role: domain.RoleAdmin},
+	service + "DeleteUser":  {role: domain.RoleAdmin},
+	service + "RestoreUser": {role: domain.RoleAdmin},
+	service + "SetPassword": {role: domain.RoleAdmin},
+	service + "GrantRole":   {role: domain.RoleAdmin},
+	service + "RevokeRole":  {role: domain.RoleAdmin},
+	service + "BanUser":     {role: domain.RoleAdmin},
+	service + "UnbanUser":   {role: domain.RoleAdmin},
+
+	service + "ListAuditEvents":       {role: domain.RoleAdmin},
+	service + "CreateWebhook":         {role: domain.RoleAdmin},
+	service + "ListWebhooks":          {role: domain.RoleAdmin},
+	service + "DeleteWebhook":         {role: domain.RoleAdmin},
+	service + "ListWebhookDeliveries": {role: domain.RoleAdmin},
+}
+
+func ruleFor(method string) rule {
+	if r, ok := permissions[method]; ok {
+		return r
+	}
+	return rule{role: domain.RoleAdmin}
+}
+
+// allows reports whether p may call a method with the rule on req. req is
+// nil for streaming methods.
+func (r rule) allows(p *Principal, req any) bool {
+	if p.atLeast(r.role) {
+		return true
+	}
+	if r.target == nil || req == nil || !p.IsUser() || !p.HasRole(domain.RoleUser) {
+		return false
+	}
+	target, err := uuid.Parse(r.target(req))
+	return err == nil && target == p.Oid
+}
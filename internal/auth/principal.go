@@ -0,0 +1,71 @@
+// Package auth identifies the callers of the user service and checks what
+// they are allowed to do.
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// Principal is the authenticated caller of an RPC: either a user with an
+// access token or a service with a client certificate.
+type Principal struct {
+	// Oid is the user the caller authenticated as. It is uuid.Nil for
+	// services.
+	Oid uuid.UUID
+	// Service is the common name of the client certificate of a service.
+	Service string
+	Roles   []domain.Role
+}
+
+// IsUser reports whether the principal is a user rather than a service.
+func (p *Principal) IsUser() bool {
+	return p.Oid != uuid.Nil
+}
+
+func (p *Principal) HasRole(role domain.Role) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p *Principal) String() string {
+	if p.IsUser() {
+		return "user " + p.Oid.String()
+	}
+	return "service " + p.Service
+}
+
+// atLeast reports whether the principal has role or a role above it. Roles
+// are ordered from RoleUser to RoleAdmin.
+func (p *Principal) atLeast(role domain.Role) bool {
+	for _, r := range p.Roles {
+		if r >= role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by the Authenticator. It
+// is not set if the caller sent no credentials.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+type authErrorKey struct{}
+
+// authError returns why the credentials sent by the caller were rejected.
+func authError(ctx context.Context) error {
+	err, _ := ctx.Value(authErrorKey{}).(error)
+	return err
+}
@@ -0,0 +1,21 @@
+// Package clientaddr carries the address of the client of an RPC in its
+// context, so that code far from the transport, like the audit log, can
+// record it. The address is resolved and stored by an interceptor of the
+// server.
+package clientaddr
+
+import "context"
+
+type key struct{}
+
+// NewContext returns a copy of ctx carrying the client address.
+func NewContext(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, key{}, addr)
+}
+
+// FromContext returns the client address of ctx, or an empty string outside
+// of an RPC.
+func FromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(key{}).(string)
+	return addr
+}
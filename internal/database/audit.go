@@ -0,0 +1,316 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/auth"
+	"github.com/sosshik/grpc-user-managment/internal/clientaddr"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/requestid"
+)
+
+// auditEvent is a change recorded by writeAudit. Before and after hold only
+// the changed fields.
+type auditEvent struct {
+	action        string
+	target        uuid.UUID
+	before, after map[string]any
+}
+
+// writeAudit records ev. It is called in the transaction making the change,
+// so that no change goes unrecorded.
+func writeAudit(ctx context.Context, db execer, ev auditEvent) error {
+	actor := domain.AnonymousActor
+	if p, ok := auth.FromContext(ctx); ok {
+		actor = p.String()
+	}
+
+	before, err := jsonOrNull(ev.before)
+	if err != nil {
+		return err
+	}
+	after, err := jsonOrNull(ev.after)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+	INSERT INTO audit_events (occurred_at, actor, target_oid, action, before, after, request_id, peer)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`, time.Now().UTC(), actor, ev.target, ev.action, before, after, requestid.FromContext(ctx), clientaddr.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("unable to write audit event: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func jsonOrNull(m map[string]any) (any, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode audit event: %w", err)
+	}
+	return string(b), nil
+}
+
+// stateNames are the names states are recorded under in audit events and
+// outbox events.
+var stateNames = map[domain.State]string{
+	domain.Active:    "USER_STATE_ACTIVE",
+	domain.Banned:    "USER_STATE_BANNED",
+	domain.Deleted:   "USER_STATE_DELETED",
+	domain.Pending:   "USER_STATE_PENDING",
+	domain.Suspended: "USER_STATE_SUSPENDED",
+}
+
+func stateFromName(name string) domain.State {
+	for state, n := range stateNames {
+		if n == name {
+			return state
+		}
+	}
+	return 0
+}
+
+// userFields returns the audited fields of a user. They keep the form audit
+// events have always been recorded in, the UserInfo JSON of the API with
+// empty fields left out. The version and timestamps of the row are left out
+// since they change with every update.
+func userFields(user *domain.User) map[string]any {
+	fields := map[string]any{}
+	if user == nil {
+		return fields
+	}
+	set := func(field, value string) {
+		if value != "" {
+			fields[field] = value
+		}
+	}
+	if user.Oid != uuid.Nil {
+		fields["oid"] = map[string]any{"value": user.Oid.String()}
+	}
+	set("nickname", user.Nickname)
+	set("email", user.Email)
+	set("first_name", user.FirstName)
+	set("last_name", user.LastName)
+	set("state", stateNames[user.State])
+	set("state_reason", user.StateReason)
+	if !user.SuspendedUntil.IsZero() {
+		fields["suspended_until"] = user.SuspendedUntil.UTC().Format(time.RFC3339Nano)
+	}
+	if !user.DeletedAt.IsZero() {
+		fields["deleted_at"] = user.DeletedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return fields
+}
+
+// diffUsers returns the fields that differ between two versions of a user.
+// Either of them may be nil for created and purged users.
+func diffUsers(before, after *domain.User) (map[string]any, map[string]any) {
+	b, a := userFields(before), userFields(after)
+	diffBefore, diffAfter := map[string]any{}, map[string]any{}
+	for field, v := range b {
+		if !reflect.DeepEqual(v, a[field]) {
+			diffBefore[field], diffAfter[field] = v, a[field]
+		}
+	}
+	for field, v := range a {
+		if _, ok := b[field]; !ok {
+			diffBefore[field], diffAfter[field] = nil, v
+		}
+	}
+	if before == nil {
+		diffBefore = nil
+	}
+	if after == nil {
+		diffAfter = nil
+	}
+	return diffBefore, diffAfter
+}
+
+func userChange(action string, oid uuid.UUID, before, after *domain.User) auditEvent {
+	ev := auditEvent{action: action, target: oid}
+	ev.before, ev.after = diffUsers(before, after)
+	return ev
+}
+
+func (d *Database) ListAuditEvents(ctx context.Context, query domain.ListAuditEventsQuery) (*domain.AuditPage, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if query.PageSize <= 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+	filterFingerprint := fingerprint(query.Filter)
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := auditConditions(query.Filter, arg)
+
+	if query.PageToken != "" {
+		cursor, err := decodeCursor(query.PageToken)
+		if err != nil || cursor.Query != filterFingerprint {
+			return nil, domain.NewFieldError(domain.ErrInvalidArgument, "page_token", "page token is malformed or does not match the request")
+		}
+		where = append(where, "id < "+arg(cursor.ID))
+	}
+
+	sqlQuery := `
+	SELECT id, occurred_at, actor, target_oid, action, before, after, request_id, peer
+	FROM audit_events`
+	if len(where) > 0 {
+		sqlQuery += "\n\tWHERE " + strings.Join(where, " AND ")
+	}
+	sqlQuery += "\n\tORDER BY id DESC\n\tLIMIT " + arg(query.PageSize+1) + ";"
+
+	rows, err := d.DB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	page := &domain.AuditPage{}
+	for rows.Next() {
+		if len(page.Events) == query.PageSize {
+			last := page.Events[len(page.Events)-1]
+			page.NextPageToken = encodeCursor(pageCursor{ID: last.ID, Query: filterFingerprint})
+			break
+		}
+		ev, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row from DB: %w", convertError(ctx, err))
+		}
+		page.Events = append(page.Events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read rows from DB: %w", convertError(ctx, err))
+	}
+	return page, nil
+}
+
+// auditConditions renders f as a list of SQL conditions. arg registers a
+// query argument and returns its placeholder.
+func auditConditions(f domain.AuditFilter, arg func(any) string) []string {
+	var where []string
+	if f.TargetOid != uuid.Nil {
+		where = append(where, "target_oid = "+arg(f.TargetOid))
+	}
+	if f.Actor != "" {
+		where = append(where, "actor = "+arg(f.Actor))
+	}
+	if len(f.Actions) > 0 {
+		actions := make([]string, 0, len(f.Actions))
+		for _, action := range f.Actions {
+			actions = append(actions, arg(action))
+		}
+		where = append(where, fmt.Sprintf("action IN (%s)", strings.Join(actions, ", ")))
+	}
+	if !f.OccurredAfter.IsZero() {
+		where = append(where, "occurred_at >= "+arg(f.OccurredAfter))
+	}
+	if !f.OccurredBefore.IsZero() {
+		where = append(where, "occurred_at < "+arg(f.OccurredBefore))
+	}
+	return where
+}
+
+func scanAuditEvent(row rowScanner) (*domain.AuditEvent, error) {
+	ev := &domain.AuditEvent{}
+	var before, after []byte
+	err := row.Scan(&ev.ID, &ev.OccurredAt, &ev.Actor, &ev.TargetOid, &ev.Action, &before, &after, &ev.RequestID, &ev.Peer)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Before, err = jsonMap(before); err != nil {
+		return nil, err
+	}
+	if ev.After, err = jsonMap(after); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func jsonMap(b []byte) (map[string]any, error) {
+	if b == nil {
+		return nil, nil
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to decode audit event: %w", err)
+	}
+	return m, nil
+}
+
+// bulkChange runs a statement of a background job and records the same
+// audit action and, unless eventType is 0, a user event for every
+// user it changed. query must be a data-modifying statement returning
+// userColumns; it runs in a CTE so the change and its records are written
+// atomically. Its arguments start at $6.
+func (d *Database) bulkChange(ctx context.Context, action string, eventType domain.EventType, before, after map[string]any, query string, args ...any) (int64, error) {
+	beforeJSON, err := jsonOrNull(before)
+	if err != nil {
+		return 0, err
+	}
+	afterJSON, err := jsonOrNull(after)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+	WITH changed AS (`+query+`),
+	audited AS (
+		INSERT INTO audit_events (occurred_at, actor, target_oid, action, before, after)
+		SELECT $1, $2, oid, $3, $4::jsonb, $5::jsonb FROM changed
+	)
+	SELECT `+userColumns+` FROM changed;
+	`, append([]any{time.Now().UTC(), domain.SystemActor, action, beforeJSON, afterJSON}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+
+	if eventType != 0 {
+		for _, user := range users {
+			if err := writeEvent(ctx, tx, eventType, user); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return int64(len(users)), nil
+}
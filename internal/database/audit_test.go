@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func TestDiffUsers(t *testing.T) {
+	before := &domain.User{Nickname: "john", Email: "john@example.com", Version: 1, State: domain.Active}
+	after := &domain.User{Nickname: "johnny", Email: "john@example.com", Version: 2, State: domain.Active}
+	suspended := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		before     *domain.User
+		after      *domain.User
+		wantBefore map[string]any
+		wantAfter  map[string]any
+	}{
+		{
+			name:       "Changed field",
+			before:     before,
+			after:      after,
+			wantBefore: map[string]any{"nickname": "john"},
+			wantAfter:  map[string]any{"nickname": "johnny"},
+		},
+		{
+			name:       "Created",
+			after:      before,
+			wantBefore: nil,
+			wantAfter:  map[string]any{"nickname": "john", "email": "john@example.com", "state": "USER_STATE_ACTIVE"},
+		},
+		{
+			name:       "Field cleared",
+			before:     &domain.User{Nickname: "john", LastName: "Doe"},
+			after:      &domain.User{Nickname: "john"},
+			wantBefore: map[string]any{"last_name": "Doe"},
+			wantAfter:  map[string]any{"last_name": nil},
+		},
+		{
+			name:       "State changed",
+			before:     &domain.User{Nickname: "john", State: domain.Active},
+			after:      &domain.User{Nickname: "john", State: domain.Suspended, StateReason: "spam", SuspendedUntil: suspended},
+			wantBefore: map[string]any{"state": "USER_STATE_ACTIVE", "state_reason": nil, "suspended_until": nil},
+			wantAfter:  map[string]any{"state": "USER_STATE_SUSPENDED", "state_reason": "spam", "suspended_until": "2024-01-01T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBefore, gotAfter := diffUsers(tt.before, tt.after)
+			if !reflect.DeepEqual(gotBefore, tt.wantBefore) {
+				t.Errorf("diffUsers() before = %v, want %v", gotBefore, tt.wantBefore)
+			}
+			if !reflect.DeepEqual(gotAfter, tt.wantAfter) {
+				t.Errorf("diffUsers() after = %v, want %v", gotAfter, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestAuditConditions(t *testing.T) {
+	oid := uuid.New()
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	got := auditConditions(domain.AuditFilter{
+		TargetOid: oid,
+		Actions:   []string{domain.ActionUserCreated, domain.ActionUserDeleted},
+	}, arg)
+
+	want := []string{"target_oid = $1", "action IN ($2, $3)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("auditConditions() = %v, want %v", got, want)
+	}
+	wantArgs := []any{oid, domain.ActionUserCreated, domain.ActionUserDeleted}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("auditConditions() args = %v, want %v", args, wantArgs)
+	}
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxConnectBackoff caps the delay between the attempts of Connect.
+const maxConnectBackoff = 30 * time.Second
+
+// ConnConfig sets up the connection pool and how Connect waits for the
+// database to come up.
+type ConnConfig struct {
+	URL             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTries is how many times the database is pinged before Connect
+	// gives up, at least once.
+	ConnectTries int
+	// ConnectBackoff is the delay after the first failed ping, doubled
+	// after every other one up to 30 seconds.
+	ConnectBackoff time.Duration
+}
+
+// Connect opens a connection pool to PostgreSQL and waits until the
+// database answers. The pool replaces broken connections by itself, so it is
+// never reopened afterwards.
+func Connect(ctx context.Context, cfg ConnConfig) (*sql.DB, error) {
+	return connect(ctx, "postgres", cfg)
+}
+
+func connect(ctx context.Context, driverName string, cfg ConnConfig) (*sql.DB, error) {
+	db, err := sql.Open(driverName, cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	tries := max(cfg.ConnectTries, 1)
+	backoff := cfg.ConnectBackoff
+	for i := 1; ; i++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+		if i == tries {
+			break
+		}
+		log.Warnf("Database is unreachable (attempt %d of %d), retrying in %s: %s", i, tries, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	db.Close()
+	return nil, fmt.Errorf("unable to connect to database after %d attempts: %w", tries, convertError(ctx, err))
+}
+
+// Monitor pings the database periodically and reports when it becomes
+// unreachable or reachable again.
+type Monitor struct {
+	DB       *sql.DB
+	Interval time.Duration
+	// OnChange is called with the result of the first ping and then every
+	// time it changes.
+	OnChange func(healthy bool)
+}
+
+// Run pings the database until ctx is done. Each ping may take up to the
+// interval.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	first := true
+	var healthy bool
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, m.Interval)
+		err := m.DB.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if first || healthy != (err == nil) {
+			healthy = err == nil
+			switch {
+			case healthy && !first:
+				log.Info("Connection to database restored")
+			case !healthy:
+				log.Warnf("Lost connection to database: %s", err)
+			}
+			first = false
+			if m.OnChange != nil {
+				m.OnChange(healthy)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/pkg/config"
+)
+
+// fakeDriver opens connections whose pings fail while down is set. The
+// pings are counted.
+type fakeDriver struct {
+	down  atomic.Bool
+	pings atomic.Int32
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.d.pings.Add(1)
+	if c.d.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+var registerOnce sync.Once
+var testDriver = &fakeDriver{}
+
+func fakeDriverName(t *testing.T) string {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("fake", testDriver) })
+	testDriver.down.Store(false)
+	testDriver.pings.Store(0)
+	return "fake"
+}
+
+func TestConnect(t *testing.T) {
+	tests := []struct {
+		name      string
+		down      bool
+		tries     int
+		wantErr   bool
+		wantPings int32
+	}{
+		{name: "Up", tries: 3, wantPings: 1},
+		{name: "Down", down: true, tries: 3, wantErr: true, wantPings: 3},
+		{name: "No tries", down: true, wantErr: true, wantPings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := fakeDriverName(t)
+			testDriver.down.Store(tt.down)
+
+			db, err := connect(context.Background(), name, ConnConfig{ConnectTries: tt.tries, ConnectBackoff: time.Millisecond})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("connect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if db != nil {
+				db.Close()
+			}
+			if got := testDriver.pings.Load(); got != tt.wantPings {
+				t.Errorf("connect() pinged %d times, want %d", got, tt.wantPings)
+			}
+		})
+	}
+}
+
+func TestConnectCanceled(t *testing.T) {
+	name := fakeDriverName(t)
+	testDriver.down.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := connect(ctx, name, ConnConfig{ConnectTries: 10, ConnectBackoff: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("connect() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	name := fakeDriverName(t)
+	db, err := connect(context.Background(), name, ConnConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	changes := make(chan bool, 10)
+	m := &Monitor{DB: db, Interval: time.Millisecond, OnChange: func(healthy bool) { changes <- healthy }}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(stopped)
+	}()
+
+	want := func(healthy bool) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != healthy {
+				t.Fatalf("Monitor reported healthy = %v, want %v", got, healthy)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Monitor did not report healthy = %v", healthy)
+		}
+	}
+	want(true)
+	testDriver.down.Store(true)
+	want(false)
+	testDriver.down.Store(false)
+	want(true)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Monitor did not stop")
+	}
+}
+
+func TestNewDatabase(t *testing.T) {
+	cfg := &config.Config{DbUrl: "postgres://user@127.0.0.1:1/db?sslmode=disable", ReconnTries: 1}
+	d, err := NewDatabase(context.Background(), cfg)
+	if err == nil {
+		d.Close()
+		t.Fatal("NewDatabase() error = nil, want an error")
+	}
+	if !errors.Is(err, domain.ErrUnavailable) {
+		t.Errorf("NewDatabase() error = %v, want %v", err, domain.ErrUnavailable)
+	}
+}
+
+func TestDatabase_withTimeout(t *testing.T) {
+	d := &Database{QueryTimeout: time.Minute}
+	ctx, cancel := d.withTimeout(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("withTimeout() deadline = %v, %v, want within a minute", deadline, ok)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), time.Second)
+	defer cancelShort()
+	ctx, cancel = d.withTimeout(short)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) > time.Second {
+		t.Errorf("withTimeout() deadline = %v, want the earlier deadline of the call", deadline)
+	}
+
+	ctx, cancel = (&Database{}).withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("withTimeout() set a deadline without QueryTimeout")
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) GetCredentials(ctx context.Context, email, nickname string) (*domain.Credentials, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	column, login := "email", email
+	if email == "" {
+		column, login = "nickname", nickname
+	}
+
+	// Deleted users may share their email and nickname with a live user.
+	return d.getCredentials(ctx, column+" = $1 AND state <> $2", login, domain.Deleted)
+}
+
+func (d *Database) GetCredentialsByID(ctx context.Context, oid uuid.UUID) (*domain.Credentials, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.getCredentials(ctx, "oid = $1", oid)
+}
+
+func (d *Database) getCredentials(ctx context.Context, where string, args ...any) (*domain.Credentials, error) {
+	creds := &domain.Credentials{}
+	var suspendedUntil sql.NullTime
+	err := d.DB.QueryRowContext(ctx, `
+	SELECT oid, password, state, suspended_until FROM users
+	WHERE `+where+`;
+	`, args...).Scan(&creds.Oid, &creds.PasswordHash, &creds.State, &suspendedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	creds.SuspendedUntil = suspendedUntil.Time
+	return creds, nil
+}
+
+// SetPassword stores a new password hash and revokes every refresh token of
+// the user, so that existing sessions have to log in again.
+func (d *Database) SetPassword(ctx context.Context, oid uuid.UUID, hash string) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	if err := setPassword(ctx, tx, domain.ActionPasswordChanged, oid, hash); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+// setPassword records the change as action, which tells apart a password
+// set by a caller from a completed reset.
+func setPassword(ctx context.Context, tx *sql.Tx, action string, oid uuid.UUID, hash string) error {
+	now := time.Now().UTC()
+	res, err := tx.ExecContext(ctx, `
+	UPDATE users
+	SET password = $2, updated_at = $3, version = version + 1
+	WHERE oid = $1 AND state <> $4;
+	`, oid, hash, now, domain.Deleted)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	if err := checkAffected(ctx, res); err != nil {
+		return err
+	}
+
+	if err := revokeUserTokens(ctx, tx, oid, now); err != nil {
+		return err
+	}
+
+	return writeAudit(ctx, tx, auditEvent{action: action, target: oid})
+}
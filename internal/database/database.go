@@ -1,176 +1,326 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 	"github.com/sosshik/grpc-user-managment/internal/domain"
 	"github.com/sosshik/grpc-user-managment/pkg/config"
-	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
 )
 
-type DBConfig struct {
-	DbUrl       string `env:"DATABASE_URL"`
-	ReconnTime  int    `env:"RECONN_TIME" envDefault:"5"`
-	ConnCheck   bool   `env:"CONN_CHECK" envDefault:"true"`
-	ReconnTries int    `env:"RECONN_TRIES" envDefault:"5"`
-}
-
 type Database struct {
-	config *DBConfig
-	DB     *sql.DB
+	DB *sql.DB
+	// QueryTimeout bounds every call unless its context ends earlier. Zero
+	// means no limit.
+	QueryTimeout time.Duration
 }
 
-var once sync.Once
-
-var dbinstance *Database
-
-func NewDatabase(cfg *config.Config) (*Database, error) {
-
-	if dbinstance == nil {
-		once.Do(func() {
-			db, err := sql.Open("postgres", cfg.DbUrl)
-			if err != nil {
-				log.Warnf("unable to create db instance: %s", err)
-			}
-
-			dbinstance = &Database{&DBConfig{cfg.DbUrl, cfg.ReconnTime, cfg.ConnCheck, cfg.ReconnTries}, db}
+// NewDatabase connects to the database, retrying with a growing delay until
+// it answers, the configured number of tries is used up or ctx is done.
+func NewDatabase(ctx context.Context, cfg *config.Config) (*Database, error) {
+	db, err := Connect(ctx, ConnConfig{
+		URL:             cfg.DbUrl,
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+		ConnectTries:    cfg.ReconnTries,
+		ConnectBackoff:  cfg.DBConnectBackoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Database{DB: db, QueryTimeout: cfg.DBQueryTimeout}, nil
+}
 
-			if cfg.ConnCheck {
-				go dbinstance.connectionCheck(cfg.DbUrl)
-			}
-		})
+// Close closes the connection pool.
+func (d *Database) Close() error {
+	return d.DB.Close()
+}
 
+// withTimeout applies QueryTimeout to ctx. Cancelling ctx cancels the running
+// query, so that a call given up on does not hold a connection.
+func (d *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-
-	return dbinstance, nil
+	return context.WithTimeout(ctx, d.QueryTimeout)
 }
 
-func (d *Database) connectionCheck(conn string) {
-	log.Info("Connection check started")
-	var i int
-	for {
-		time.Sleep(time.Duration(d.config.ReconnTime) * time.Second)
-		if err := d.DB.Ping(); err != nil {
-			log.Warnf("Lost connection to Database. Attempting to reconnect.")
-			if err := d.DB.Close(); err != nil {
-				log.Warnf("Error while disconecting: %s", err)
-				continue
-			}
-			if i <= d.config.ReconnTries {
-				d.DB, err = sql.Open("postgres", conn)
-				if err != nil {
-					log.Warnf("Failed to reconnect: %s", err)
-					i++
-				} else {
-					log.Infof("Reconnected to PostgreSQL!")
-					i = 0
-				}
-			} else {
-				break
-			}
+func (d *Database) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
 
-		}
+	if user.Oid == uuid.Nil {
+		return domain.NewFieldError(domain.ErrInvalidArgument, "oid", "oid is required")
 	}
-}
+	oid := user.Oid
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
 
-func (d *Database) CreateUser(user *proto.UserInfo, pass string, state domain.State) error {
 	t := time.Now().UTC()
-	_, err := d.DB.Exec(`
+	created, err := scanUser(tx.QueryRowContext(ctx, `
 	INSERT INTO users (oid, nickname, email, first_name, last_name, password, created_at, updated_at, state)
-	VALUES ($1, $2, $3, $4, $5, $6, $7,$8, $9);
-	`, user.Oid.GetValue(), user.Nickname, user.Email, user.FirstName, user.LastName, pass, t, t, state)
+	VALUES ($1, $2, $3, $4, $5, $6, $7,$8, $9)
+	RETURNING `+userColumns+`;
+	`, oid, user.Nickname, user.Email, user.FirstName, user.LastName, user.PasswordHash, t, t, user.State))
 	if err != nil {
-		return fmt.Errorf("unable to execute query to DB: %w", err)
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO user_roles (user_oid, role, granted_at)
+	VALUES ($1, $2, $3);
+	`, oid, domain.RoleUser, t)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	if err := writeAudit(ctx, tx, userChange(domain.ActionUserCreated, oid, nil, created)); err != nil {
+		return err
 	}
+	if err := writeEvent(ctx, tx, domain.EventCreated, created); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	created.PasswordHash = user.PasswordHash
+	*user = *created
 	return nil
 }
 
-func (d *Database) GetUserByEmail(email string) (*proto.UserInfo, error) {
-	user := &proto.UserInfo{Oid: &proto.UUID{}}
-	err := d.DB.QueryRow(`
-	SELECT oid, nickname, email, first_name, last_name FROM users
-	WHERE email = $1;
-	`, email).Scan(&user.Oid.Value, &user.Nickname, &user.Email, &user.FirstName, &user.LastName)
-	if err != nil && err != sql.ErrNoRows {
-		return &proto.UserInfo{}, fmt.Errorf("unable to execute query to DB: %w", err)
+func (d *Database) GetUserByEmail(ctx context.Context, email string, includeDeleted bool) (*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(d.DB.QueryRowContext(ctx, `
+	SELECT `+userColumns+` FROM users
+	WHERE email = $1 AND ($2 OR state <> $3)
+	ORDER BY state = $3, deleted_at DESC
+	LIMIT 1;
+	`, email, includeDeleted, domain.Deleted))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
 	}
 	return user, nil
 }
 
-func (d *Database) GetUserByID(oid uuid.UUID) (*proto.UserInfo, error) {
-	user := &proto.UserInfo{Oid: &proto.UUID{}}
-	err := d.DB.QueryRow(`
-	SELECT oid, nickname, email, first_name, last_name FROM users
-	WHERE oid = $1;
-	`, oid).Scan(&user.Oid.Value, &user.Nickname, &user.Email, &user.FirstName, &user.LastName)
-	if err != nil && err != sql.ErrNoRows {
-		return &proto.UserInfo{}, fmt.Errorf("unable to execute query to DB: %w", err)
+func (d *Database) GetUserByID(ctx context.Context, oid uuid.UUID, includeDeleted bool) (*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(d.DB.QueryRowContext(ctx, `
+	SELECT `+userColumns+` FROM users
+	WHERE oid = $1 AND ($2 OR state <> $3);
+	`, oid, includeDeleted, domain.Deleted))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
 	}
 	return user, nil
 }
 
-func (d *Database) GetUsers() ([]*proto.UserInfo, error) {
-	rows, err := d.DB.Query(`
-	SELECT oid, nickname, email, first_name, last_name
-    FROM users
-	`)
+// updatableColumns maps domain.UserFields to the columns UpdateUser may
+// change.
+var updatableColumns = map[string]string{
+	"nickname":   "nickname",
+	"email":      "email",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+}
+
+func (d *Database) UpdateUser(ctx context.Context, user *domain.User, fields []string) (*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	oid := user.Oid
+	values := map[string]any{
+		"nickname":   user.Nickname,
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+
+	args := []any{oid, time.Now().UTC()}
+	set := []string{"updated_at=$2", "version=version+1"}
+	for _, field := range fields {
+		column, ok := updatableColumns[field]
+		if !ok {
+			return nil, domain.NewFieldError(domain.ErrInvalidArgument, "update_mask", fmt.Sprintf("field %q can't be updated", field))
+		}
+		args = append(args, values[field])
+		set = append(set, fmt.Sprintf("%s=$%d", column, len(args)))
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return []*proto.UserInfo{}, fmt.Errorf("unable to execute query to DB: %w", err)
+		return nil, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var users []*proto.UserInfo
+	before, err := lockUser(ctx, tx, oid, user.Version)
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		user := &proto.UserInfo{Oid: &proto.UUID{}}
-		err := rows.Scan(&user.Oid.Value, &user.Nickname, &user.Email, &user.FirstName, &user.LastName)
-		if err != nil {
-			return []*proto.UserInfo{}, fmt.Errorf("unable to scan row from DB: %w", err)
-		}
+	updated, err := scanUser(tx.QueryRowContext(ctx, `
+	UPDATE users
+	SET `+strings.Join(set, ", ")+`
+	WHERE oid=$1
+	RETURNING `+userColumns+`;
+	`, args...))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
 
-		users = append(users, &proto.UserInfo{
-			Oid:       &proto.UUID{Value: user.Oid.Value},
-			Email:     user.Email,
-			Nickname:  user.Nickname,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-		})
+	if err := writeAudit(ctx, tx, userChange(domain.ActionUserUpdated, oid, before, updated)); err != nil {
+		return nil, err
 	}
-	return users, nil
+	if err := writeEvent(ctx, tx, domain.EventUpdated, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return updated, nil
 }
 
-func (d *Database) UpdateUser(user *proto.UserInfo) error {
+// DeleteUser marks the user Deleted. Deleted users are hidden from reads
+// until RestoreUser brings them back or PurgeDeletedUsers removes them.
+func (d *Database) DeleteUser(ctx context.Context, oid uuid.UUID, version int64) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
 
-	oid, err := uuid.Parse(user.Oid.GetValue())
+	before, err := lockUser(ctx, tx, oid, version)
 	if err != nil {
-		return fmt.Errorf("unable to parse uuid: %w", err)
+		return err
 	}
 
-	_, err = d.DB.Exec(`
+	now := time.Now().UTC()
+	deleted, err := scanUser(tx.QueryRowContext(ctx, `
 	UPDATE users
-	SET nickname=$1, email = $2, first_name=$3, last_name=$4, updated_at=$5
-	WHERE oid=$6;
-	`, user.Nickname, user.Email, user.FirstName, user.LastName, time.Now().UTC(), oid)
+	SET state_before_delete = state, state = $2, deleted_at = $3, updated_at = $3, version = version + 1
+	WHERE oid = $1
+	RETURNING `+userColumns+`;
+	`, oid, domain.Deleted, now))
 	if err != nil {
-		return fmt.Errorf("unable to execute query to DB: %w", err)
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	if err := revokeUserTokens(ctx, tx, oid, now); err != nil {
+		return err
+	}
+
+	if err := writeAudit(ctx, tx, userChange(domain.ActionUserDeleted, oid, before, deleted)); err != nil {
+		return err
+	}
+	if err := writeEvent(ctx, tx, domain.EventDeleted, deleted); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
 	}
 	return nil
 }
 
-func (d *Database) DeleteUser(oid uuid.UUID) error {
+// lockUser reads a user that is not Deleted for update. A non-zero version
+// must match the stored one.
+func lockUser(ctx context.Context, tx *sql.Tx, oid uuid.UUID, version int64) (*domain.User, error) {
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+	SELECT `+userColumns+` FROM users
+	WHERE oid = $1 AND state <> $2
+	FOR UPDATE;
+	`, oid, domain.Deleted))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	if version != 0 && user.Version != version {
+		return nil, fmt.Errorf("etag does not match the stored user: %w", domain.ErrConflict)
+	}
+	return user, nil
+}
+
+func (d *Database) RestoreUser(ctx context.Context, oid uuid.UUID) (*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
 
-	_, err := d.DB.Exec(`
-	DELETE FROM users
-    WHERE oid = $1;
-	`, oid)
+	tx, err := d.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("unable to execute query to DB: %w", err)
+		return nil, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
 	}
+	defer tx.Rollback()
 
+	before, err := scanUser(tx.QueryRowContext(ctx, `
+	SELECT `+userColumns+` FROM users
+	WHERE oid = $1
+	FOR UPDATE;
+	`, oid))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	if before.State != domain.Deleted {
+		return nil, fmt.Errorf("user is not deleted: %w", domain.ErrFailedPrecondition)
+	}
+
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+	UPDATE users
+	SET state = state_before_delete, state_before_delete = NULL, deleted_at = NULL, updated_at = $2, version = version + 1
+	WHERE oid = $1
+	RETURNING `+userColumns+`;
+	`, oid, time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	if err := writeAudit(ctx, tx, userChange(domain.ActionUserRestored, oid, before, user)); err != nil {
+		return nil, err
+	}
+	if err := writeEvent(ctx, tx, domain.EventUpdated, user); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return user, nil
+}
+
+// PurgeDeletedUsers publishes no events, consumers were told about the
+// deletion already.
+func (d *Database) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.bulkChange(ctx, domain.ActionUserPurged, 0, nil, nil, `
+	DELETE FROM users
+	WHERE state = $6 AND deleted_at < $7
+	RETURNING `+userColumns, domain.Deleted, deletedBefore)
+}
+
+// checkAffected reports domain.ErrNotFound when a statement matched no rows.
+func checkAffected(ctx context.Context, res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows: %w", convertError(ctx, err))
+	}
+	if n == 0 {
+		return fmt.Errorf("user %w", domain.ErrNotFound)
+	}
 	return nil
 }
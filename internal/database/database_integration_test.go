@@ -0,0 +1,152 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDatabase returns a Database on a migrated schema of its own.
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	db := newTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return &Database{DB: db}
+}
+
+func createTestUser(t *testing.T, d *Database, nickname, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{
+		Oid:          uuid.New(),
+		Nickname:     nickname,
+		Email:        email,
+		FirstName:    "John",
+		LastName:     "Doe",
+		PasswordHash: "hash",
+		State:        domain.Active,
+	}
+	if err := d.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestDatabase_DeletedUserKeys(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDatabase(t)
+
+	deleted := createTestUser(t, d, "john", "john@example.com")
+	if err := d.DeleteUser(ctx, deleted.Oid, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The email and nickname of a deleted user are free to take.
+	taken := createTestUser(t, d, "john", "john@example.com")
+
+	creds, err := d.GetCredentials(ctx, "john@example.com", "")
+	if err != nil {
+		t.Fatalf("GetCredentials() error = %v", err)
+	}
+	assert.Equal(t, taken.Oid, creds.Oid)
+
+	user, err := d.GetUserByEmail(ctx, "john@example.com", true)
+	if err != nil {
+		t.Fatalf("GetUserByEmail() error = %v", err)
+	}
+	assert.Equal(t, taken.Oid, user.Oid)
+
+	_, err = d.RestoreUser(ctx, deleted.Oid)
+	var fieldErr *domain.FieldError
+	if !errors.As(err, &fieldErr) || !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("RestoreUser() error = %v, want ErrAlreadyExists", err)
+	}
+	assert.Contains(t, []string{"email", "nickname"}, fieldErr.Field)
+
+	// Live users still can't share them.
+	err = d.CreateUser(ctx, &domain.User{Oid: uuid.New(), Nickname: "johnny", Email: "john@example.com", State: domain.Active})
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+}
+
+// createTestTokens gives the user a token of every kind.
+func createTestTokens(t *testing.T, d *Database, oid uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	token := &domain.RefreshToken{TokenHash: uuid.NewString(), FamilyID: uuid.New(), UserOid: oid, ExpiresAt: expiresAt}
+	if err := d.CreateRefreshToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateResetToken(ctx, oid, uuid.NewString(), expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateVerificationToken(ctx, oid, uuid.NewString(), expiresAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countTokens returns how many tokens of any kind the user has.
+func countTokens(t *testing.T, d *Database, oid uuid.UUID) int {
+	t.Helper()
+	var n int
+	err := d.DB.QueryRow(`
+	SELECT (SELECT COUNT(*) FROM refresh_tokens WHERE user_oid = $1)
+		+ (SELECT COUNT(*) FROM reset_tokens WHERE user_oid = $1)
+		+ (SELECT COUNT(*) FROM verification_tokens WHERE user_oid = $1);
+	`, oid).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDatabase_PurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDatabase(t)
+
+	user := createTestUser(t, d, "john", "john@example.com")
+	createTestTokens(t, d, user.Oid)
+	if err := d.DeleteUser(ctx, user.Oid, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := d.PurgeDeletedUsers(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeDeletedUsers() error = %v", err)
+	}
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 0, countTokens(t, d, user.Oid))
+}
+
+func TestDatabase_DeleteUnverifiedUsers(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDatabase(t)
+
+	pending := &domain.User{Oid: uuid.New(), Nickname: "john", Email: "john@example.com", PasswordHash: "hash", State: domain.Pending}
+	if err := d.CreateUser(ctx, pending); err != nil {
+		t.Fatal(err)
+	}
+	createTestTokens(t, d, pending.Oid)
+	active := createTestUser(t, d, "jane", "jane@example.com")
+	createTestTokens(t, d, active.Oid)
+
+	n, err := d.DeleteUnverifiedUsers(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteUnverifiedUsers() error = %v", err)
+	}
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 0, countTokens(t, d, pending.Oid))
+	assert.Equal(t, 3, countTokens(t, d, active.Oid))
+}
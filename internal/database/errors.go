@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// uniqueViolation is the field error reported for a unique constraint.
+type uniqueViolation struct {
+	field, description string
+}
+
+// uniqueViolations are the unique constraints callers can run into. Others
+// are reported as a plain ErrAlreadyExists.
+var uniqueViolations = map[string]uniqueViolation{
+	"users_email_unique_idx":    {"email", "user with this email already exists"},
+	"users_nickname_unique_idx": {"nickname", "user with this nickname already exists"},
+	"users_oid_key":             {"oid", "user with this oid already exists"},
+	"webhooks_pkey":             {"id", "webhook with this id already exists"},
+	"user_roles_pkey":           {"role", "user already has this role"},
+}
+
+// convertError translates driver and database/sql errors into the domain
+// error kinds, keeping the original error in the chain. ctx is the context
+// the failed call ran with.
+func convertError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return convertPQError(ctx, pqErr)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+func convertPQError(ctx context.Context, err *pq.Error) error {
+	if err.Code.Name() == "query_canceled" {
+		// The statement outlived the context of the call, which either the
+		// client cancelled or the query timeout cut short.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return fmt.Errorf("%w: %w", context.Canceled, err)
+		}
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	switch err.Code.Class() {
+	case "08", "53", "57":
+		// connection exception, insufficient resources, operator intervention
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	case "22":
+		// data exception: invalid text representation, value too long, etc.
+		return invalidColumn(err)
+	case "23":
+		switch err.Code.Name() {
+		case "unique_violation":
+			if v, ok := uniqueViolations[err.Constraint]; ok {
+				return fmt.Errorf("%w: %w", domain.NewFieldError(domain.ErrAlreadyExists, v.field, v.description), err)
+			}
+			return fmt.Errorf("%w: %w", domain.ErrAlreadyExists, err)
+		case "foreign_key_violation":
+			// the referenced user does not exist
+			return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+		case "not_null_violation", "check_violation":
+			return invalidColumn(err)
+		}
+	}
+	// Everything else, insufficient_privilege included, is a fault of the
+	// server and not of the caller.
+	return err
+}
+
+// invalidColumn reports err as an invalid value of its column, or as a plain
+// ErrInvalidArgument if Postgres did not name the column.
+func invalidColumn(err *pq.Error) error {
+	if err.Column == "" {
+		return fmt.Errorf("%w: %w", domain.ErrInvalidArgument, err)
+	}
+	return fmt.Errorf("%w: %w", domain.NewFieldError(domain.ErrInvalidArgument, err.Column, err.Message), err)
+}
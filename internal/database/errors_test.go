@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func TestConvertError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{name: "No rows", err: sql.ErrNoRows, want: domain.ErrNotFound},
+		{name: "Unique violation", err: &pq.Error{Code: "23505", Constraint: "users_email_unique_idx"}, want: domain.ErrAlreadyExists},
+		{name: "Foreign key violation", err: &pq.Error{Code: "23503", Constraint: "user_roles_user_oid_fkey"}, want: domain.ErrNotFound},
+		{name: "Invalid text representation", err: &pq.Error{Code: "22P02"}, want: domain.ErrInvalidArgument},
+		{name: "Connection failure", err: &pq.Error{Code: "08006"}, want: domain.ErrUnavailable},
+		{name: "Cannot connect now", err: &pq.Error{Code: "57P03"}, want: domain.ErrUnavailable},
+		{name: "Query timed out", err: &pq.Error{Code: "57014"}, want: context.DeadlineExceeded},
+		{name: "Query canceled by client", ctx: canceled, err: &pq.Error{Code: "57014"}, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := convertError(ctx, tt.err); !errors.Is(got, tt.want) {
+				t.Errorf("convertError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var fieldErr *domain.FieldError
+	if err := convertError(context.Background(), &pq.Error{Code: "23505", Constraint: "users_nickname_unique_idx"}); !errors.As(err, &fieldErr) || fieldErr.Field != "nickname" {
+		t.Errorf("convertError() field = %v, want nickname", err)
+	}
+}
+
+func TestConvertErrorDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *pq.Error
+		want      error
+		wantField string
+	}{
+		{name: "Known unique constraint", err: &pq.Error{Code: "23505", Constraint: "user_roles_pkey"}, want: domain.ErrAlreadyExists, wantField: "role"},
+		{name: "Other unique constraint", err: &pq.Error{Code: "23505", Constraint: "webhook_deliveries_webhook_id_event_offset_key"}, want: domain.ErrAlreadyExists},
+		{name: "Data exception with column", err: &pq.Error{Code: "22001", Column: "nickname"}, want: domain.ErrInvalidArgument, wantField: "nickname"},
+		{name: "Data exception without column", err: &pq.Error{Code: "22P02"}, want: domain.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := convertError(context.Background(), tt.err)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("convertError() = %v, want %v", err, tt.want)
+			}
+			var fieldErr *domain.FieldError
+			if hasField := errors.As(err, &fieldErr); hasField != (tt.wantField != "") || hasField && fieldErr.Field != tt.wantField {
+				t.Errorf("convertError() field error = %v, want field %q", fieldErr, tt.wantField)
+			}
+		})
+	}
+
+	// Missing privileges are a misconfiguration of the server, not an error
+	// of the caller.
+	if err := convertError(context.Background(), &pq.Error{Code: "42501"}); errors.Is(err, domain.ErrPermissionDenied) {
+		t.Errorf("convertError() = %v, want no domain error", err)
+	}
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// orderColumns maps the supported sort keys to the SQL type used to compare
+// cursor values against them.
+var orderColumns = map[string]string{
+	"created_at": "timestamptz",
+	"nickname":   "varchar",
+	"email":      "varchar",
+}
+
+// pageCursor is the decoded form of a page token. It stores the sort key and
+// id of the last returned row, and a fingerprint of the query it belongs to.
+type pageCursor struct {
+	Key   string `json:"k"`
+	ID    int64  `json:"i"`
+	Query string `json:"q"`
+}
+
+func (d *Database) ListUsers(ctx context.Context, query domain.ListUsersQuery) (*domain.UserPage, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if query.OrderBy == "" {
+		query.OrderBy = "created_at"
+	}
+	castType, ok := orderColumns[query.OrderBy]
+	if !ok {
+		return nil, domain.NewFieldError(domain.ErrInvalidArgument, "order_by", fmt.Sprintf("unsupported order %q", query.OrderBy))
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+
+	fingerprint := queryFingerprint(query)
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := filterConditions(query.Filter, arg)
+
+	direction, cmp := "ASC", ">"
+	if query.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if query.PageToken != "" {
+		cursor, err := decodeCursor(query.PageToken)
+		if err != nil || cursor.Query != fingerprint {
+			return nil, domain.NewFieldError(domain.ErrInvalidArgument, "page_token", "page token is malformed or does not match the request")
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+			query.OrderBy, cmp, arg(cursor.Key), castType, arg(cursor.ID)))
+	}
+
+	sqlQuery := `
+	SELECT ` + userColumns + `, id, created_at
+	FROM users`
+	if len(where) > 0 {
+		sqlQuery += "\n\tWHERE " + strings.Join(where, " AND ")
+	}
+	sqlQuery += fmt.Sprintf("\n\tORDER BY %[1]s %[2]s, id %[2]s\n\tLIMIT %[3]s;", query.OrderBy, direction, arg(query.PageSize+1))
+
+	rows, err := d.DB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	page := &domain.UserPage{}
+	var lastID int64
+	var lastCreated time.Time
+	for rows.Next() {
+		if len(page.Users) == query.PageSize {
+			last := page.Users[len(page.Users)-1]
+			cursor := pageCursor{ID: lastID, Query: fingerprint}
+			switch query.OrderBy {
+			case "created_at":
+				cursor.Key = lastCreated.Format(time.RFC3339Nano)
+			case "nickname":
+				cursor.Key = last.Nickname
+			case "email":
+				cursor.Key = last.Email
+			}
+			page.NextPageToken = encodeCursor(cursor)
+			break
+		}
+
+		user, err := scanUser(rows, &lastID, &lastCreated)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row from DB: %w", convertError(ctx, err))
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read rows from DB: %w", convertError(ctx, err))
+	}
+
+	return page, nil
+}
+
+// filterConditions renders f as a list of SQL conditions. arg registers a
+// query argument and returns its placeholder.
+func filterConditions(f domain.UserFilter, arg func(any) string) []string {
+	var where []string
+	if !f.IncludeDeleted {
+		where = append(where, "state <> "+arg(domain.Deleted))
+	}
+	if len(f.States) > 0 {
+		states := make([]string, 0, len(f.States))
+		for _, st := range f.States {
+			states = append(states, arg(st))
+		}
+		where = append(where, fmt.Sprintf("state IN (%s)", strings.Join(states, ", ")))
+	}
+	if f.NicknamePrefix != "" {
+		where = append(where, "nickname LIKE "+arg(likePrefix(f.NicknamePrefix)))
+	}
+	if f.EmailPrefix != "" {
+		where = append(where, "email LIKE "+arg(likePrefix(f.EmailPrefix)))
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= "+arg(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		where = append(where, "created_at < "+arg(f.CreatedBefore))
+	}
+	return where
+}
+
+// likePrefix escapes LIKE wildcards in s and turns it into a prefix pattern.
+func likePrefix(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s) + "%"
+}
+
+// queryFingerprint identifies the filter and ordering of a query so that a
+// page token can't be replayed against a different one.
+func queryFingerprint(query domain.ListUsersQuery) string {
+	return fingerprint(struct {
+		Filter  domain.UserFilter
+		OrderBy string
+		Desc    bool
+	}{query.Filter, query.OrderBy, query.Desc})
+}
+
+func fingerprint(v any) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
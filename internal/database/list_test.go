@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func TestLikePrefix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "john", want: "john%"},
+		{in: "50%_off", want: `50\%\_off%`},
+		{in: `back\slash`, want: `back\\slash%`},
+	}
+	for _, tt := range tests {
+		if got := likePrefix(tt.in); got != tt.want {
+			t.Errorf("likePrefix(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	q1 := domain.ListUsersQuery{OrderBy: "nickname"}
+	q2 := domain.ListUsersQuery{OrderBy: "nickname", Filter: domain.UserFilter{EmailPrefix: "a"}}
+
+	want := pageCursor{Key: "john", ID: 42, Query: queryFingerprint(q1)}
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("decodeCursor() = %v, want %v", got, want)
+	}
+	if queryFingerprint(q1) == queryFingerprint(q2) {
+		t.Errorf("queryFingerprint() is equal for different filters")
+	}
+	if _, err := decodeCursor("not a token"); err == nil {
+		t.Errorf("decodeCursor() expected error for malformed token")
+	}
+}
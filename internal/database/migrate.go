@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	log "github.com/sirupsen/logrus"
+	"github.com/sosshik/grpc-user-managment/migrations"
+)
+
+// Migrator applies the schema migrations embedded in the binary. The applied
+// versions are kept in the goose_db_version table. Every run holds a Postgres
+// advisory lock, so replicas started together migrate one after another.
+type Migrator struct {
+	db       *sql.DB
+	provider *goose.Provider
+	// locker takes the lock on a connection of its own for the whole run.
+	// Nil runs without a lock.
+	locker lock.SessionLocker
+}
+
+// MigrationStatus is a migration and when it was applied. AppliedAt is zero
+// for pending migrations.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create migration lock: %w", err)
+	}
+	return newMigrator(db, goose.DialectPostgres, migrations.FS, locker)
+}
+
+func newMigrator(db *sql.DB, dialect goose.Dialect, fsys fs.FS, locker lock.SessionLocker) (*Migrator, error) {
+	provider, err := goose.NewProvider(dialect, db, fsys, goose.WithDisableGlobalRegistry(true))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load migrations: %w", err)
+	}
+	return &Migrator{db: db, provider: provider, locker: locker}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func() error {
+		results, err := m.provider.Up(ctx)
+		logResults(results...)
+		if err != nil {
+			return fmt.Errorf("unable to apply migrations: %w", err)
+		}
+		if len(results) == 0 {
+			log.Info("Database schema is up to date")
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func() error {
+		return m.down(ctx)
+	})
+}
+
+// Redo rolls back the last applied migration and applies it again. Both
+// steps run under the same lock, so that no other replica migrates in
+// between.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.locked(ctx, func() error {
+		if err := m.down(ctx); err != nil {
+			return err
+		}
+		result, err := m.provider.UpByOne(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to apply migration: %w", err)
+		}
+		logResults(result)
+		return nil
+	})
+}
+
+func (m *Migrator) down(ctx context.Context) error {
+	result, err := m.provider.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return errors.New("no migration to roll back")
+	}
+	if err != nil {
+		return fmt.Errorf("unable to roll back migration: %w", err)
+	}
+	logResults(result)
+	return nil
+}
+
+// locked runs fn holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if m.locker == nil {
+		return fn()
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get connection: %w", convertError(ctx, err))
+	}
+	defer conn.Close()
+
+	if err := m.locker.SessionLock(ctx, conn); err != nil {
+		return fmt.Errorf("unable to take migration lock: %w", err)
+	}
+	defer func() {
+		if err := m.locker.SessionUnlock(context.Background(), conn); err != nil {
+			log.Warnf("unable to release migration lock: %s", err)
+		}
+	}()
+	return fn()
+}
+
+// Status lists the migrations in order of their versions.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migration status: %w", err)
+	}
+	list := make([]MigrationStatus, 0, len(statuses))
+	for _, st := range statuses {
+		status := MigrationStatus{Version: st.Source.Version, Name: path.Base(st.Source.Path)}
+		if st.State == goose.StateApplied {
+			status.AppliedAt = st.AppliedAt
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+func logResults(results ...*goose.MigrationResult) {
+	for _, res := range results {
+		if res == nil || res.Error != nil {
+			continue
+		}
+		log.Infof("Migrated %s %s in %s", res.Direction, path.Base(res.Source.Path), res.Duration)
+	}
+}
@@ -0,0 +1,161 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDB connects to the Postgres database at TEST_DATABASE_URL, a
+// postgres:// URL, with a schema of its own as the search path. The schema is
+// dropped when the test ends. The test is skipped if the variable is unset.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("unable to drop schema %s: %s", schema, err)
+		}
+		admin.Close()
+	})
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// countingLocker counts the times the lock is taken.
+type countingLocker struct {
+	locked, unlocked int
+}
+
+func (l *countingLocker) SessionLock(ctx context.Context, conn *sql.Conn) error {
+	l.locked++
+	return nil
+}
+
+func (l *countingLocker) SessionUnlock(ctx context.Context, conn *sql.Conn) error {
+	l.unlocked++
+	return nil
+}
+
+// applied returns whether each migration of m is applied.
+func applied(t *testing.T, m *Migrator) []bool {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []bool
+	for _, st := range statuses {
+		got = append(got, !st.AppliedAt.IsZero())
+	}
+	return got
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	fsys := fstest.MapFS{
+		"001_create_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE a (id INTEGER);\n\n-- +goose Down\nDROP TABLE a;\n")},
+		"002_create_b.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id INTEGER);\n\n-- +goose Down\nDROP TABLE b;\n")},
+	}
+	locker := &countingLocker{}
+	m, err := newMigrator(db, goose.DialectPostgres, fsys, locker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	assert.Equal(t, []bool{true, true}, applied(t, m))
+	assert.True(t, tableExists(t, db, "b"))
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	assert.Equal(t, []bool{true, false}, applied(t, m))
+	assert.False(t, tableExists(t, db, "b"))
+
+	if err := m.Redo(ctx); err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	assert.Equal(t, []bool{true, false}, applied(t, m))
+	assert.True(t, tableExists(t, db, "a"))
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	assert.Error(t, m.Down(ctx), "Down() with nothing applied")
+	assert.Error(t, m.Redo(ctx), "Redo() with nothing applied")
+
+	// Every run, Redo included, takes the lock once and releases it.
+	assert.Equal(t, 6, locker.locked)
+	assert.Equal(t, locker.locked, locker.unlocked)
+}
+
+// TestMigrations applies every embedded migration, rolls them all back and
+// applies them again, so that the down migrations are checked as well.
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if _, err := m.provider.DownTo(ctx, 0); err != nil {
+		t.Fatalf("DownTo(0) error = %v", err)
+	}
+	assert.False(t, tableExists(t, db, "users"))
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after DownTo(0) error = %v", err)
+	}
+	assert.True(t, tableExists(t, db, "users"))
+}
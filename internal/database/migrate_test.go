@@ -0,0 +1,29 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMigratorSources(t *testing.T) {
+	// The provider reads the embedded files only, no connection is made.
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, src := range sources {
+		if src.Version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d", src.Path, src.Version, i+1)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// outboxLockID is the key of the advisory lock held while offsets are
+// assigned to outbox events.
+const outboxLockID = 0x6f7574626f78
+
+// eventTypeNames are the names event types are stored under in the outbox
+// and in the subscriptions of webhooks.
+var eventTypeNames = map[domain.EventType]string{
+	domain.EventCreated: "USER_EVENT_TYPE_CREATED",
+	domain.EventUpdated: "USER_EVENT_TYPE_UPDATED",
+	domain.EventDeleted: "USER_EVENT_TYPE_DELETED",
+}
+
+func eventTypeFromName(name string) domain.EventType {
+	for t, n := range eventTypeNames {
+		if n == name {
+			return t
+		}
+	}
+	return 0
+}
+
+// eventUser is the payload of outbox events. It keeps the JSON form of the
+// API's UserInfo the first events were written in, so that events written
+// before an upgrade can still be read.
+type eventUser struct {
+	Oid struct {
+		Value uuid.UUID `json:"value"`
+	} `json:"oid"`
+	Nickname       string     `json:"nickname,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	FirstName      string     `json:"firstName,omitempty"`
+	LastName       string     `json:"lastName,omitempty"`
+	Etag           string     `json:"etag,omitempty"`
+	State          string     `json:"state,omitempty"`
+	StateReason    string     `json:"stateReason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
+}
+
+func encodeEventUser(user *domain.User) ([]byte, error) {
+	e := eventUser{
+		Nickname:       user.Nickname,
+		Email:          user.Email,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Etag:           strconv.FormatInt(user.Version, 10),
+		State:          stateNames[user.State],
+		StateReason:    user.StateReason,
+		SuspendedUntil: timeOrNil(user.SuspendedUntil),
+		DeletedAt:      timeOrNil(user.DeletedAt),
+		CreatedAt:      timeOrNil(user.CreatedAt),
+		UpdatedAt:      timeOrNil(user.UpdatedAt),
+	}
+	e.Oid.Value = user.Oid
+	return json.Marshal(e)
+}
+
+func decodeEventUser(payload []byte) (*domain.User, error) {
+	var e eventUser
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	user := &domain.User{
+		Oid:            e.Oid.Value,
+		Nickname:       e.Nickname,
+		Email:          e.Email,
+		FirstName:      e.FirstName,
+		LastName:       e.LastName,
+		State:          stateFromName(e.State),
+		StateReason:    e.StateReason,
+		SuspendedUntil: timeValue(e.SuspendedUntil),
+		DeletedAt:      timeValue(e.DeletedAt),
+		CreatedAt:      timeValue(e.CreatedAt),
+		UpdatedAt:      timeValue(e.UpdatedAt),
+	}
+	if e.Etag != "" {
+		version, err := strconv.ParseInt(e.Etag, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed etag: %w", err)
+		}
+		user.Version = version
+	}
+	return user, nil
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// writeEvent adds a lifecycle event of the user to the outbox. It is called
+// in the transaction making the change, so that an event is published for
+// every committed change and for nothing else.
+func writeEvent(ctx context.Context, db execer, eventType domain.EventType, user *domain.User) error {
+	payload, err := encodeEventUser(user)
+	if err != nil {
+		return fmt.Errorf("unable to encode user event: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+	INSERT INTO outbox (occurred_at, event_type, user_oid, payload)
+	VALUES ($1, $2, $3, $4);
+	`, time.Now().UTC(), eventTypeNames[eventType], user.Oid, string(payload))
+	if err != nil {
+		return fmt.Errorf("unable to write user event: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) PendingUserEvents(ctx context.Context, limit int) ([]*domain.UserEvent, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if err := d.sequenceUserEvents(ctx, limit); err != nil {
+		return nil, err
+	}
+
+	return d.queryUserEvents(ctx, `
+	WHERE event_offset IS NOT NULL AND published_at IS NULL
+	ORDER BY event_offset
+	LIMIT $1;
+	`, limit)
+}
+
+// sequenceUserEvents assigns offsets to up to limit events. Events of
+// transactions committed out of order get their offsets when they become
+// visible, so offsets never go back in time. The advisory lock keeps
+// concurrent relays from interleaving.
+func (d *Database) sequenceUserEvents(ctx context.Context, limit int) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1);`, outboxLockID); err != nil {
+		return fmt.Errorf("unable to lock outbox: %w", convertError(ctx, err))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT id FROM outbox
+	WHERE event_offset IS NULL
+	ORDER BY id
+	LIMIT $1;
+	`, limit)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+
+	for _, id := range ids {
+		_, err := tx.ExecContext(ctx, `
+		UPDATE outbox SET event_offset = nextval('outbox_offset_seq')
+		WHERE id = $1;
+		`, id)
+		if err != nil {
+			return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) MarkUserEventsPublished(ctx context.Context, upTo int64) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	_, err := d.DB.ExecContext(ctx, `
+	UPDATE outbox SET published_at = $2
+	WHERE event_offset <= $1 AND published_at IS NULL;
+	`, upTo, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) ListUserEvents(ctx context.Context, afterOffset int64, limit int) ([]*domain.UserEvent, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.queryUserEvents(ctx, `
+	WHERE event_offset > $1
+	ORDER BY event_offset
+	LIMIT $2;
+	`, afterOffset, limit)
+}
+
+func (d *Database) FirstUserEventOffset(ctx context.Context) (int64, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	var first int64
+	err := d.DB.QueryRowContext(ctx, `
+	SELECT COALESCE(
+		(SELECT min(event_offset) FROM outbox),
+		(SELECT CASE WHEN is_called THEN last_value + 1 ELSE last_value END FROM outbox_offset_seq)
+	);
+	`).Scan(&first)
+	if err != nil {
+		return 0, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return first, nil
+}
+
+func (d *Database) PurgeUserEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	res, err := d.DB.ExecContext(ctx, `
+	DELETE FROM outbox
+	WHERE published_at < $1;
+	`, publishedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("unable to get affected rows: %w", convertError(ctx, err))
+	}
+	return n, nil
+}
+
+func (d *Database) queryUserEvents(ctx context.Context, where string, args ...any) ([]*domain.UserEvent, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+	SELECT event_offset, event_type, occurred_at, payload FROM outbox`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	var events []*domain.UserEvent
+	for rows.Next() {
+		ev, err := scanUserEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+	return events, nil
+}
+
+func scanUserEvent(row rowScanner) (*domain.UserEvent, error) {
+	ev := &domain.UserEvent{}
+	var (
+		eventType string
+		payload   []byte
+	)
+	if err := row.Scan(&ev.Offset, &eventType, &ev.OccurredAt, &payload); err != nil {
+		return nil, err
+	}
+	ev.Type = eventTypeFromName(eventType)
+	user, err := decodeEventUser(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode user event: %w", err)
+	}
+	ev.User = user
+	return ev, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// eventRow is an outbox row as read by queryUserEvents.
+type eventRow struct {
+	offset     int64
+	eventType  string
+	occurredAt time.Time
+	payload    []byte
+}
+
+func (r eventRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = r.offset
+	*dest[1].(*string) = r.eventType
+	*dest[2].(*time.Time) = r.occurredAt
+	*dest[3].(*[]byte) = r.payload
+	return nil
+}
+
+func TestScanUserEvent(t *testing.T) {
+	user := &domain.User{
+		Oid:            uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd"),
+		Nickname:       "john",
+		State:          domain.Suspended,
+		SuspendedUntil: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Version:        3,
+	}
+	payload, err := encodeEventUser(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ev, err := scanUserEvent(eventRow{
+		offset:     42,
+		eventType:  "USER_EVENT_TYPE_UPDATED",
+		occurredAt: occurredAt,
+		payload:    payload,
+	})
+	if err != nil {
+		t.Fatalf("scanUserEvent() error = %v", err)
+	}
+	if ev.Offset != 42 || ev.Type != domain.EventUpdated || !ev.OccurredAt.Equal(occurredAt) {
+		t.Errorf("scanUserEvent() = %v", ev)
+	}
+	if !reflect.DeepEqual(ev.User, user) {
+		t.Errorf("scanUserEvent() user = %v, want %v", ev.User, user)
+	}
+
+	if _, err := scanUserEvent(eventRow{payload: []byte("not json")}); err == nil {
+		t.Errorf("scanUserEvent() expected error for malformed payload")
+	}
+}
+
+// Events written before the outbox had its own payload type hold the
+// protobuf JSON of UserInfo.
+func TestDecodeEventUser(t *testing.T) {
+	payload := []byte(`{"oid":{"value":"e93b6308-fbc2-40a7-90fc-84627f1580dd"},"nickname":"john","firstName":"John","etag":"3","state":"USER_STATE_BANNED","stateReason":"spam"}`)
+	want := &domain.User{
+		Oid:         uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd"),
+		Nickname:    "john",
+		FirstName:   "John",
+		State:       domain.Banned,
+		StateReason: "spam",
+		Version:     3,
+	}
+
+	got, err := decodeEventUser(payload)
+	if err != nil {
+		t.Fatalf("decodeEventUser() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeEventUser() = %v, want %v", got, want)
+	}
+}
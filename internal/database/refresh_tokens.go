@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	_, err := d.DB.ExecContext(ctx, `
+	INSERT INTO refresh_tokens (token_hash, family_id, user_oid, expires_at)
+	VALUES ($1, $2, $3, $4);
+	`, token.TokenHash, token.FamilyID, token.UserOid, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) RotateRefreshToken(ctx context.Context, hash string, next *domain.RefreshToken) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	var (
+		expiresAt         time.Time
+		usedAt, revokedAt sql.NullTime
+		creds             domain.Credentials
+		suspendedUntil    sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `
+	SELECT t.family_id, t.user_oid, t.expires_at, t.used_at, t.revoked_at, u.state, u.suspended_until
+	FROM refresh_tokens t
+	JOIN users u ON u.oid = t.user_oid
+	WHERE t.token_hash = $1
+	FOR UPDATE OF t;
+	`, hash).Scan(&next.FamilyID, &next.UserOid, &expiresAt, &usedAt, &revokedAt, &creds.State, &suspendedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("unknown refresh token: %w", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	creds.SuspendedUntil = suspendedUntil.Time
+	switch {
+	case revokedAt.Valid:
+		return fmt.Errorf("refresh token is revoked: %w", domain.ErrUnauthenticated)
+	case usedAt.Valid, !creds.CanLogIn(time.Now()):
+		// A rotated token showing up again means it leaked; the family is
+		// revoked so that neither party can keep using it.
+		if err := revokeFamily(ctx, tx, next.FamilyID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+		}
+		return fmt.Errorf("refresh token was reused or user is locked: %w", domain.ErrUnauthenticated)
+	case time.Now().After(expiresAt):
+		return fmt.Errorf("refresh token is expired: %w", domain.ErrUnauthenticated)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE refresh_tokens SET used_at = $2
+	WHERE token_hash = $1;
+	`, hash, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO refresh_tokens (token_hash, family_id, user_oid, expires_at)
+	VALUES ($1, $2, $3, $4);
+	`, next.TokenHash, next.FamilyID, next.UserOid, next.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+// RevokeRefreshToken succeeds for unknown and already revoked tokens as
+// well, so that callers can't tell which tokens exist (RFC 7009).
+func (d *Database) RevokeRefreshToken(ctx context.Context, hash string) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	_, err := d.DB.ExecContext(ctx, `
+	UPDATE refresh_tokens SET revoked_at = $2
+	WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+		AND revoked_at IS NULL;
+	`, hash, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func revokeFamily(ctx context.Context, db execer, familyID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `
+	UPDATE refresh_tokens SET revoked_at = $2
+	WHERE family_id = $1 AND revoked_at IS NULL;
+	`, familyID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
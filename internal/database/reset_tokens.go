@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) CreateResetToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.createSingleUseToken(ctx, "reset_tokens", oid, hash, expiresAt)
+}
+
+func (d *Database) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	oid, err := consumeSingleUseToken(ctx, tx, "reset_tokens", tokenHash)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := setPassword(ctx, tx, domain.ActionPasswordReset, oid, passwordHash); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return oid, nil
+}
+
+// createSingleUseToken stores a token hash in table, which has the layout of
+// reset_tokens. Earlier unused tokens of the user are invalidated, so only the
+// latest one stays usable.
+func (d *Database) createSingleUseToken(ctx context.Context, table string, oid uuid.UUID, hash string, expiresAt time.Time) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE `+table+` SET used_at = $2
+	WHERE user_oid = $1 AND used_at IS NULL;
+	`, oid, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO `+table+` (token_hash, user_oid, expires_at)
+	VALUES ($1, $2, $3);
+	`, hash, oid, expiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+// consumeSingleUseToken marks a token as used and returns its user. Unknown,
+// used and expired tokens yield a field error on "token".
+func consumeSingleUseToken(ctx context.Context, tx *sql.Tx, table, hash string) (uuid.UUID, error) {
+	var oid uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+	UPDATE `+table+` SET used_at = $2
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+	RETURNING user_oid;
+	`, hash, time.Now().UTC()).Scan(&oid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, domain.NewFieldError(domain.ErrInvalidArgument, "token", "token is invalid or expired")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return oid, nil
+}
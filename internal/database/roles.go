@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) GetRoles(ctx context.Context, oid uuid.UUID) ([]domain.Role, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, `
+	SELECT role FROM user_roles
+	WHERE user_oid = $1
+	ORDER BY role;
+	`, oid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+	return roles, nil
+}
+
+// GrantRole and RevokeRole only record an audit event when the roles of the
+// user actually change.
+func (d *Database) GrantRole(ctx context.Context, oid uuid.UUID, role domain.Role) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.changeRole(ctx, `
+	INSERT INTO user_roles (user_oid, role)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING;
+	`, auditEvent{action: domain.ActionRoleGranted, target: oid, after: map[string]any{"role": role.String()}}, role)
+}
+
+func (d *Database) RevokeRole(ctx context.Context, oid uuid.UUID, role domain.Role) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.changeRole(ctx, `
+	DELETE FROM user_roles
+	WHERE user_oid = $1 AND role = $2;
+	`, auditEvent{action: domain.ActionRoleRevoked, target: oid, before: map[string]any{"role": role.String()}}, role)
+}
+
+func (d *Database) changeRole(ctx context.Context, query string, ev auditEvent, role domain.Role) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query, ev.target, role)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows: %w", convertError(ctx, err))
+	}
+	if n > 0 {
+		if err := writeAudit(ctx, tx, ev); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
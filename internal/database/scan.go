@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// userColumns are the columns read by scanUser, in order.
+const userColumns = "oid, nickname, email, first_name, last_name, version, state, state_reason, suspended_until, deleted_at, created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser reads userColumns followed by any extra columns into a User.
+func scanUser(row rowScanner, extra ...any) (*domain.User, error) {
+	user := &domain.User{}
+	var suspendedUntil, deletedAt sql.NullTime
+	dest := append([]any{&user.Oid, &user.Nickname, &user.Email, &user.FirstName, &user.LastName, &user.Version,
+		&user.State, &user.StateReason, &suspendedUntil, &deletedAt, &user.CreatedAt, &user.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	user.SuspendedUntil = suspendedUntil.Time
+	user.DeletedAt = deletedAt.Time
+	return user, nil
+}
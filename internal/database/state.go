@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) ChangeState(ctx context.Context, oid uuid.UUID, change domain.StateChange) (*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	before, err := lockUser(ctx, tx, oid, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.CheckTransition(before.State, change.State); err != nil {
+		return nil, err
+	}
+
+	var until sql.NullTime
+	if change.State == domain.Suspended {
+		until = sql.NullTime{Time: change.Until.UTC(), Valid: true}
+	}
+	now := time.Now().UTC()
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+	UPDATE users
+	SET state = $2, state_reason = $3, suspended_until = $4, updated_at = $5, version = version + 1
+	WHERE oid = $1
+	RETURNING `+userColumns+`;
+	`, oid, change.State, change.Reason, until, now))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	if change.State == domain.Banned || change.State == domain.Suspended {
+		if err := revokeUserTokens(ctx, tx, oid, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeAudit(ctx, tx, userChange(domain.ActionUserStateChanged, oid, before, user)); err != nil {
+		return nil, err
+	}
+	if err := writeEvent(ctx, tx, domain.EventUpdated, user); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return user, nil
+}
+
+func (d *Database) LiftSuspensions(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.bulkChange(ctx, domain.ActionSuspensionEnded, domain.EventUpdated,
+		map[string]any{"state": stateNames[domain.Suspended]},
+		map[string]any{"state": stateNames[domain.Active]}, `
+	UPDATE users
+	SET state = $7, state_reason = 'suspension ended', suspended_until = NULL, updated_at = $8, version = version + 1
+	WHERE state = $6 AND suspended_until <= $8
+	RETURNING `+userColumns, domain.Suspended, domain.Active, now.UTC())
+}
+
+// revokeUserTokens revokes every refresh token of the user.
+func revokeUserTokens(ctx context.Context, db execer, oid uuid.UUID, now time.Time) error {
+	_, err := db.ExecContext(ctx, `
+	UPDATE refresh_tokens SET revoked_at = $2
+	WHERE user_oid = $1 AND revoked_at IS NULL;
+	`, oid, now)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
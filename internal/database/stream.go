@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+const streamBatchSize = 500
+
+// StreamUsers calls fn for every user matching filter in created_at order.
+// Users are read in keyset batches, so neither the database connection nor
+// the process holds more than one batch at a time. It stops at the first
+// error returned by fn or when ctx is done.
+func (d *Database) StreamUsers(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	var (
+		lastID      int64
+		lastCreated time.Time
+		first       = true
+	)
+	for {
+		var args []any
+		arg := func(v any) string {
+			args = append(args, v)
+			return fmt.Sprintf("$%d", len(args))
+		}
+		where := filterConditions(filter, arg)
+		if !first {
+			where = append(where, fmt.Sprintf("(created_at, id) > (%s, %s)", arg(lastCreated), arg(lastID)))
+		}
+
+		query := `
+	SELECT ` + userColumns + `, id, created_at
+	FROM users`
+		if len(where) > 0 {
+			query += "\n\tWHERE " + strings.Join(where, " AND ")
+		}
+		query += fmt.Sprintf("\n\tORDER BY created_at, id\n\tLIMIT %d;", streamBatchSize)
+
+		batch, err := d.queryBatch(ctx, query, args, &lastID, &lastCreated)
+		if err != nil {
+			return err
+		}
+		for _, user := range batch {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		if len(batch) < streamBatchSize {
+			return nil
+		}
+		first = false
+	}
+}
+
+// queryBatch applies the query timeout to each batch rather than to the
+// whole stream.
+func (d *Database) queryBatch(ctx context.Context, query string, args []any, lastID *int64, lastCreated *time.Time) ([]*domain.User, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	batch := make([]*domain.User, 0, streamBatchSize)
+	for rows.Next() {
+		user, err := scanUser(rows, lastID, lastCreated)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row from DB: %w", convertError(ctx, err))
+		}
+		batch = append(batch, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read rows from DB: %w", convertError(ctx, err))
+	}
+	return batch, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) CreateVerificationToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.createSingleUseToken(ctx, "verification_tokens", oid, hash, expiresAt)
+}
+
+func (d *Database) VerifyEmail(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	oid, err := consumeSingleUseToken(ctx, tx, "verification_tokens", tokenHash)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+	UPDATE users
+	SET state = $2, updated_at = $4, version = version + 1
+	WHERE oid = $1 AND state = $3
+	RETURNING `+userColumns+`;
+	`, oid, domain.Active, domain.Pending, time.Now().UTC()))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+
+	err = writeAudit(ctx, tx, auditEvent{
+		action: domain.ActionEmailVerified,
+		target: oid,
+		before: map[string]any{"state": stateNames[domain.Pending]},
+		after:  map[string]any{"state": stateNames[domain.Active]},
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := writeEvent(ctx, tx, domain.EventUpdated, user); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return oid, nil
+}
+
+func (d *Database) DeleteUnverifiedUsers(ctx context.Context, createdBefore time.Time) (int64, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	return d.bulkChange(ctx, domain.ActionUnverifiedDeleted, domain.EventDeleted, nil, nil, `
+	DELETE FROM users
+	WHERE state = $6 AND created_at < $7
+	RETURNING `+userColumns, domain.Pending, createdBefore)
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+func (d *Database) CreateWebhook(ctx context.Context, webhook *domain.Webhook, secret string) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	names := make([]string, 0, len(webhook.EventTypes))
+	for _, t := range webhook.EventTypes {
+		names = append(names, eventTypeNames[t])
+	}
+	err := d.DB.QueryRowContext(ctx, `
+	INSERT INTO webhooks (id, url, event_types, secret, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING created_at;
+	`, webhook.ID, webhook.URL, pq.Array(names), secret, time.Now().UTC()).Scan(&webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, `
+	SELECT id, url, event_types, created_at FROM webhooks
+	ORDER BY created_at;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.Webhook
+	for rows.Next() {
+		webhook := &domain.Webhook{}
+		var eventTypes []string
+		if err := rows.Scan(&webhook.ID, &webhook.URL, pq.Array(&eventTypes), &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		for _, name := range eventTypes {
+			webhook.EventTypes = append(webhook.EventTypes, eventTypeFromName(name))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+	return webhooks, nil
+}
+
+func (d *Database) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	res, err := d.DB.ExecContext(ctx, `
+	DELETE FROM webhooks
+	WHERE id = $1;
+	`, id)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows: %w", convertError(ctx, err))
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (d *Database) EnqueueWebhookDeliveries(ctx context.Context, events []*domain.WebhookEvent) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", convertError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, ev := range events {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_offset, payload, state, created_at, next_attempt_at)
+		SELECT id, $1, $2, $3, $4, $4 FROM webhooks
+		WHERE cardinality(event_types) = 0 OR $5 = ANY(event_types)
+		ON CONFLICT (webhook_id, event_offset) DO NOTHING;
+		`, ev.Offset, string(ev.Payload), domain.DeliveryPending, now, eventTypeNames[ev.Type])
+		if err != nil {
+			return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.PendingDelivery, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, `
+	UPDATE webhook_deliveries d
+	SET next_attempt_at = $2
+	FROM webhooks w
+	WHERE w.id = d.webhook_id AND d.id IN (
+		SELECT id FROM webhook_deliveries
+		WHERE state = $3 AND next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING d.id, d.attempts, d.payload, w.url, w.secret;
+	`, now.UTC(), now.Add(lease).UTC(), domain.DeliveryPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	var claimed []*domain.PendingDelivery
+	for rows.Next() {
+		p := &domain.PendingDelivery{}
+		if err := rows.Scan(&p.ID, &p.Attempts, &p.Payload, &p.URL, &p.Secret); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", convertError(ctx, err))
+		}
+		claimed = append(claimed, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to iterate rows: %w", convertError(ctx, err))
+	}
+	return claimed, nil
+}
+
+func (d *Database) RecordDeliveryAttempt(ctx context.Context, attempt domain.DeliveryAttempt) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	var next sql.NullTime
+	if attempt.State == domain.DeliveryPending {
+		next = sql.NullTime{Time: attempt.NextAttemptAt.UTC(), Valid: true}
+	}
+	_, err := d.DB.ExecContext(ctx, `
+	UPDATE webhook_deliveries
+	SET state = $2, attempts = attempts + 1, last_attempt_at = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6
+	WHERE id = $1;
+	`, attempt.DeliveryID, attempt.State, attempt.At.UTC(), attempt.StatusCode, attempt.Error, next)
+	if err != nil {
+		return fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	return nil
+}
+
+func (d *Database) ListWebhookDeliveries(ctx context.Context, query domain.ListDeliveriesQuery) (*domain.DeliveryPage, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if query.PageSize <= 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+	filterFingerprint := fingerprint(query.Filter)
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var where []string
+	if query.Filter.WebhookID != uuid.Nil {
+		where = append(where, "webhook_id = "+arg(query.Filter.WebhookID))
+	}
+	if len(query.Filter.States) > 0 {
+		states := make([]int64, 0, len(query.Filter.States))
+		for _, state := range query.Filter.States {
+			states = append(states, int64(state))
+		}
+		where = append(where, "state = ANY("+arg(pq.Array(states))+")")
+	}
+	if query.PageToken != "" {
+		cursor, err := decodeCursor(query.PageToken)
+		if err != nil || cursor.Query != filterFingerprint {
+			return nil, domain.NewFieldError(domain.ErrInvalidArgument, "page_token", "page token is malformed or does not match the request")
+		}
+		where = append(where, "id < "+arg(cursor.ID))
+	}
+
+	sqlQuery := `
+	SELECT id, webhook_id, payload, state, attempts, created_at, next_attempt_at, last_attempt_at, last_status_code, last_error
+	FROM webhook_deliveries`
+	if len(where) > 0 {
+		sqlQuery += "\n\tWHERE " + strings.Join(where, " AND ")
+	}
+	sqlQuery += "\n\tORDER BY id DESC\n\tLIMIT " + arg(query.PageSize+1) + ";"
+
+	rows, err := d.DB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute query to DB: %w", convertError(ctx, err))
+	}
+	defer rows.Close()
+
+	page := &domain.DeliveryPage{}
+	for rows.Next() {
+		if len(page.Deliveries) == query.PageSize {
+			last := page.Deliveries[len(page.Deliveries)-1]
+			page.NextPageToken = encodeCursor(pageCursor{ID: last.ID, Query: filterFingerprint})
+			break
+		}
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row from DB: %w", convertError(ctx, err))
+		}
+		page.Deliveries = append(page.Deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read rows from DB: %w", convertError(ctx, err))
+	}
+	return page, nil
+}
+
+func scanDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	delivery := &domain.WebhookDelivery{}
+	var nextAttemptAt, lastAttemptAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Payload, &delivery.State, &delivery.Attempts, &delivery.CreatedAt,
+		&nextAttemptAt, &lastAttemptAt, &delivery.LastStatusCode, &delivery.LastError)
+	if err != nil {
+		return nil, err
+	}
+	delivery.NextAttemptAt = nextAttemptAt.Time
+	delivery.LastAttemptAt = lastAttemptAt.Time
+	return delivery, nil
+}
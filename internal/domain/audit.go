@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionUserCreated       = "user.created"
+	ActionUserUpdated       = "user.updated"
+	ActionUserDeleted       = "user.deleted"
+	ActionUserRestored      = "user.restored"
+	ActionUserPurged        = "user.purged"
+	ActionUserStateChanged  = "user.state_changed"
+	ActionEmailVerified     = "user.email_verified"
+	ActionPasswordChanged   = "user.password_changed"
+	ActionPasswordReset     = "user.password_reset"
+	ActionRoleGranted       = "user.role_granted"
+	ActionRoleRevoked       = "user.role_revoked"
+	ActionSuspensionEnded   = "user.suspension_ended"
+	ActionUnverifiedDeleted = "user.unverified_deleted"
+)
+
+// Actors of audit events that are not made by a caller.
+const (
+	// SystemActor makes changes of background jobs.
+	SystemActor = "system"
+	// AnonymousActor makes changes of unauthenticated calls such as
+	// CreateUser or VerifyEmail.
+	AnonymousActor = "anonymous"
+)
+
+// AuditFilter restricts the events returned by ListAuditEvents. Zero values
+// are ignored.
+type AuditFilter struct {
+	TargetOid      uuid.UUID
+	Actor          string
+	Actions        []string
+	OccurredAfter  time.Time
+	OccurredBefore time.Time
+}
+
+// ListAuditEventsQuery describes a page of audit events, newest first.
+type ListAuditEventsQuery struct {
+	Filter    AuditFilter
+	PageSize  int
+	PageToken string
+}
+
+// AuditEvent is a recorded change. Before and After hold the changed fields
+// of the target, either is nil for created and removed users.
+type AuditEvent struct {
+	ID         int64
+	OccurredAt time.Time
+	Actor      string
+	TargetOid  uuid.UUID
+	Action     string
+	Before     map[string]any
+	After      map[string]any
+	RequestID  string
+	Peer       string
+}
+
+type AuditPage struct {
+	Events        []*AuditEvent
+	NextPageToken string
+}
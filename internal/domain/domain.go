@@ -1,25 +1,221 @@
 package domain
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
-	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
 )
 
+// Role grants access to RPCs beyond the ones open to everybody. Every user
+// is given RoleUser on creation.
 type Role int
 
+const (
+	// RoleUser allows reading and updating one's own account.
+	RoleUser Role = iota + 1
+	// RoleSupport additionally allows reading any account.
+	RoleSupport
+	// RoleAdmin allows everything.
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleUser:
+		return "user"
+	case RoleSupport:
+		return "support"
+	case RoleAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
 type State int
 
 const (
 	Deleted State = iota - 1
 	Banned
 	Active
+	// Pending users have not verified their email yet.
+	Pending
+	// Suspended users can't log in until their suspension ends.
+	Suspended
 )
 
+// UserFilter restricts the users returned by ListUsers. Zero values are
+// ignored.
+type UserFilter struct {
+	States         []State
+	NicknamePrefix string
+	EmailPrefix    string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	// IncludeDeleted also returns Deleted users, which are hidden otherwise.
+	IncludeDeleted bool
+}
+
+// ListUsersQuery describes a single page request. OrderBy is one of
+// created_at, nickname or email. PageToken is the NextPageToken of the
+// previous page and is only valid with the same Filter and ordering.
+type ListUsersQuery struct {
+	Filter    UserFilter
+	OrderBy   string
+	Desc      bool
+	PageSize  int
+	PageToken string
+}
+
+type UserPage struct {
+	Users         []*User
+	NextPageToken string
+}
+
+// Credentials is the stored login data of a user.
+type Credentials struct {
+	Oid          uuid.UUID
+	PasswordHash string
+	State        State
+	// SuspendedUntil is set for Suspended users.
+	SuspendedUntil time.Time
+}
+
+// StateChange moves a user to another state, such as banning it.
+type StateChange struct {
+	State State
+	// Reason is shown to clients alongside the state.
+	Reason string
+	// Until is the end of a suspension and is only used with Suspended.
+	Until time.Time
+}
+
+// RefreshToken is a stored refresh token. Only the hash of the token is
+// kept. Tokens obtained by rotating one another share a FamilyID.
+type RefreshToken struct {
+	TokenHash string
+	FamilyID  uuid.UUID
+	UserOid   uuid.UUID
+	ExpiresAt time.Time
+}
+
+// DomainInterface is the storage used by the API. Lookups, updates and deletes
+// of a user that does not exist return an error wrapping ErrNotFound.
+//
+// Every method runs its queries under ctx, so that cancelling a call frees
+// its database connection. Changes to users, their state, password and roles
+// record an audit event in the same transaction, with the actor, request id
+// and peer address read from ctx. Changes to the fields of a User also write
+// a UserEvent to the outbox in that transaction, to be published by the
+// outbox relay.
 type DomainInterface interface {
-	CreateUser(user *proto.UserInfo, pass string, state State) error
-	GetUserByID(oid uuid.UUID) (*proto.UserInfo, error)
-	GetUserByEmail(email string) (*proto.UserInfo, error)
-	GetUsers() ([]*proto.UserInfo, error)
-	UpdateUser(user *proto.UserInfo) error
-	DeleteUser(oid uuid.UUID) error
+	// CreateUser stores the user with its PasswordHash and State and fills
+	// in the fields set by the database, such as CreatedAt and Version.
+	CreateUser(ctx context.Context, user *User) error
+	// GetUserByID and GetUserByEmail treat Deleted users as missing unless
+	// includeDeleted is set. Deleted users don't hold on to their email and
+	// nickname, so GetUserByEmail prefers the live user sharing the email,
+	// then the one deleted last.
+	GetUserByID(ctx context.Context, oid uuid.UUID, includeDeleted bool) (*User, error)
+	GetUserByEmail(ctx context.Context, email string, includeDeleted bool) (*User, error)
+	ListUsers(ctx context.Context, query ListUsersQuery) (*UserPage, error)
+	StreamUsers(ctx context.Context, filter UserFilter, fn func(*User) error) error
+	// UpdateUser changes only the listed UserFields (e.g. "last_name") and
+	// returns the stored user. A non-zero user.Version must match the
+	// stored version, otherwise ErrConflict is returned. The same applies to
+	// the version passed to DeleteUser.
+	UpdateUser(ctx context.Context, user *User, fields []string) (*User, error)
+	// DeleteUser only marks the user Deleted, RestoreUser undoes it and
+	// brings back the state it had before. Deleted users can't be updated.
+	// RestoreUser yields ErrAlreadyExists if another user took the email or
+	// nickname in the meantime.
+	DeleteUser(ctx context.Context, oid uuid.UUID, version int64) error
+	RestoreUser(ctx context.Context, oid uuid.UUID) (*User, error)
+	// PurgeDeletedUsers removes users deleted before the given time for
+	// good, together with their roles and tokens, and returns how many were
+	// removed.
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// GetCredentials looks a user that is not Deleted up by email or, if
+	// email is empty, by nickname.
+	GetCredentials(ctx context.Context, email, nickname string) (*Credentials, error)
+	GetCredentialsByID(ctx context.Context, oid uuid.UUID) (*Credentials, error)
+	// SetPassword replaces the password hash of the user and revokes all of
+	// its refresh tokens.
+	SetPassword(ctx context.Context, oid uuid.UUID, hash string) error
+	// CreateResetToken stores a password reset token of the user and
+	// invalidates the ones issued before.
+	CreateResetToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error
+	// ResetPassword consumes a reset token and sets the password of its user
+	// like SetPassword does. Unknown, used and expired tokens yield
+	// ErrInvalidArgument.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error)
+	// CreateVerificationToken stores an email verification token of the user
+	// and invalidates the ones issued before.
+	CreateVerificationToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error
+	// VerifyEmail consumes a verification token and promotes its Pending
+	// user to Active.
+	VerifyEmail(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	// DeleteUnverifiedUsers removes Pending users created before the given
+	// time, together with their roles and tokens, and returns how many were
+	// removed.
+	DeleteUnverifiedUsers(ctx context.Context, createdBefore time.Time) (int64, error)
+	// ChangeState moves the user to change.State and returns the stored user.
+	// Transitions not allowed by CheckTransition yield
+	// ErrFailedPrecondition and Deleted users ErrNotFound. Banning and
+	// suspending a user revokes its refresh tokens; its access tokens are
+	// refused from the next call on.
+	ChangeState(ctx context.Context, oid uuid.UUID, change StateChange) (*User, error)
+	// LiftSuspensions activates Suspended users whose suspension ended
+	// before now and returns how many were activated. Like the other
+	// background cleanups it is audited with SystemActor.
+	LiftSuspensions(ctx context.Context, now time.Time) (int64, error)
+	// GetRoles returns the roles of the user, it is empty if the user has
+	// none or does not exist.
+	GetRoles(ctx context.Context, oid uuid.UUID) ([]Role, error)
+	// GrantRole gives the role to the user. Granting a role the user already
+	// has is not an error.
+	GrantRole(ctx context.Context, oid uuid.UUID, role Role) error
+	RevokeRole(ctx context.Context, oid uuid.UUID, role Role) error
+	ListAuditEvents(ctx context.Context, query ListAuditEventsQuery) (*AuditPage, error)
+	// PendingUserEvents assigns offsets to outbox events in the order they
+	// became visible and returns up to limit events that were not published
+	// yet, ordered by offset.
+	PendingUserEvents(ctx context.Context, limit int) ([]*UserEvent, error)
+	// MarkUserEventsPublished marks the events up to the offset published.
+	MarkUserEventsPublished(ctx context.Context, upTo int64) error
+	// ListUserEvents returns up to limit events with an offset greater than
+	// afterOffset, ordered by offset.
+	ListUserEvents(ctx context.Context, afterOffset int64, limit int) ([]*UserEvent, error)
+	// FirstUserEventOffset returns the offset of the oldest event in the
+	// outbox or, if there is none, the offset the next event will get.
+	FirstUserEventOffset(ctx context.Context) (int64, error)
+	// PurgeUserEvents removes events published before the given time and
+	// returns how many were removed.
+	PurgeUserEvents(ctx context.Context, publishedBefore time.Time) (int64, error)
+	// CreateWebhook stores the webhook and fills in its creation time.
+	CreateWebhook(ctx context.Context, webhook *Webhook, secret string) error
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	// DeleteWebhook removes the webhook along with its deliveries.
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	ListWebhookDeliveries(ctx context.Context, query ListDeliveriesQuery) (*DeliveryPage, error)
+	// EnqueueWebhookDeliveries creates a pending delivery of every event for
+	// each webhook subscribed to its type. Events enqueued before are
+	// skipped.
+	EnqueueWebhookDeliveries(ctx context.Context, events []*WebhookEvent) error
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at
+	// now and postpones them by lease, so that other senders leave them
+	// alone while they are attempted.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*PendingDelivery, error)
+	RecordDeliveryAttempt(ctx context.Context, attempt DeliveryAttempt) error
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	// RotateRefreshToken marks the token with the given hash as used and
+	// stores next in its family, filling in next.FamilyID and next.UserOid.
+	// Unknown, expired and revoked tokens, as well as tokens of users that
+	// can't log in, yield ErrUnauthenticated. Presenting a token
+	// that was already rotated revokes its whole family.
+	RotateRefreshToken(ctx context.Context, hash string, next *RefreshToken) error
+	// RevokeRefreshToken revokes the family of the token with the given hash.
+	// Unknown and already revoked tokens are not an error.
+	RevokeRefreshToken(ctx context.Context, hash string) error
 }
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds returned by DomainInterface implementations. Callers should
+// match them with errors.Is rather than comparing error strings.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrUnavailable      = errors.New("unavailable")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	// ErrConflict means a precondition such as an etag no longer holds.
+	ErrConflict = errors.New("conflict")
+	// ErrFailedPrecondition means the user is not in a state that allows
+	// the operation, e.g. unbanning a user that is not banned.
+	ErrFailedPrecondition = errors.New("failed precondition")
+)
+
+// FieldError describes a failure caused by the value of a single field,
+// e.g. a malformed oid or an email that is already taken. Kind is one of
+// the Err* values above.
+type FieldError struct {
+	Field       string
+	Description string
+	Kind        error
+}
+
+func NewFieldError(kind error, field, description string) *FieldError {
+	return &FieldError{Field: field, Description: description, Kind: kind}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Description)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Kind
+}
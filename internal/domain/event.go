@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType is the kind of change a UserEvent reports.
+type EventType int
+
+const (
+	EventCreated EventType = iota + 1
+	// EventUpdated is also used for state changes and restored users.
+	EventUpdated
+	EventDeleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// UserEvent is a lifecycle event of a user written to the outbox.
+type UserEvent struct {
+	// Offset orders the events. It is assigned when the event is sequenced
+	// for publishing.
+	Offset     int64
+	Type       EventType
+	OccurredAt time.Time
+	// User is the user as of the change.
+	User *User
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+func (s State) String() string {
+	switch s {
+	case Deleted:
+		return "deleted"
+	case Banned:
+		return "banned"
+	case Active:
+		return "active"
+	case Pending:
+		return "pending"
+	case Suspended:
+		return "suspended"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// transitions lists the states each state may be changed to with
+// ChangeState. Pending users only become Active by verifying their email.
+var transitions = map[State][]State{
+	Pending:   {Banned, Deleted},
+	Active:    {Banned, Suspended, Deleted},
+	Suspended: {Active, Banned, Suspended, Deleted},
+	Banned:    {Active, Deleted},
+	Deleted:   {},
+}
+
+// CheckTransition returns an error wrapping ErrFailedPrecondition if a user
+// in state from can't be moved to state to.
+func CheckTransition(from, to State) error {
+	if !slices.Contains(transitions[from], to) {
+		return fmt.Errorf("user can't be %s, it is %s: %w", to, from, ErrFailedPrecondition)
+	}
+	return nil
+}
+
+// CanLogIn reports whether a user with the credentials may log in at now.
+// A suspension that has ended counts as Active even before LiftSuspensions
+// catches up with it.
+func (c *Credentials) CanLogIn(now time.Time) bool {
+	switch c.State {
+	case Active:
+		return true
+	case Suspended:
+		return !now.Before(c.SuspendedUntil)
+	}
+	return false
+}
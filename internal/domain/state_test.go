@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to State
+		wantErr  bool
+	}{
+		{from: Active, to: Banned},
+		{from: Active, to: Suspended},
+		{from: Suspended, to: Suspended},
+		{from: Suspended, to: Active},
+		{from: Banned, to: Active},
+		{from: Pending, to: Banned},
+		{from: Pending, to: Active, wantErr: true},
+		{from: Banned, to: Suspended, wantErr: true},
+		{from: Active, to: Active, wantErr: true},
+		{from: Deleted, to: Active, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := CheckTransition(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckTransition(%s, %s) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrFailedPrecondition) {
+			t.Errorf("CheckTransition(%s, %s) error = %v, want %v", tt.from, tt.to, err, ErrFailedPrecondition)
+		}
+	}
+}
+
+func TestCredentials_CanLogIn(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		creds Credentials
+		want  bool
+	}{
+		{name: "Active", creds: Credentials{State: Active}, want: true},
+		{name: "Pending", creds: Credentials{State: Pending}},
+		{name: "Banned", creds: Credentials{State: Banned}},
+		{name: "Suspended", creds: Credentials{State: Suspended, SuspendedUntil: now.Add(time.Hour)}},
+		{name: "Suspension ended", creds: Credentials{State: Suspended, SuspendedUntil: now.Add(-time.Hour)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.creds.CanLogIn(now); got != tt.want {
+				t.Errorf("Credentials.CanLogIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
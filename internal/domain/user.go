@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxNameLen is the length limit of the text fields of a user, in
+// characters.
+const maxNameLen = 255
+
+// UserFields are the fields of a user clients can set, as named by
+// UpdateUser and Validate.
+var UserFields = []string{"nickname", "email", "first_name", "last_name"}
+
+// User is a stored user account.
+type User struct {
+	Oid       uuid.UUID
+	Nickname  string
+	Email     string
+	FirstName string
+	LastName  string
+	// PasswordHash is only used by CreateUser, users read back leave it
+	// empty. Credentials carries it for logging in.
+	PasswordHash string
+	State        State
+	// StateReason explains the last state change.
+	StateReason string
+	// SuspendedUntil is set for Suspended users.
+	SuspendedUntil time.Time
+	// DeletedAt is set for Deleted users.
+	DeletedAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Version grows with every change of the user. It is 0 for users not
+	// stored yet.
+	Version int64
+}
+
+// Validate checks the listed fields of u, or all of UserFields if none are
+// listed. Failures are FieldErrors wrapping ErrInvalidArgument.
+func (u *User) Validate(fields ...string) error {
+	if len(fields) == 0 {
+		fields = UserFields
+	}
+	values := map[string]string{
+		"nickname":   u.Nickname,
+		"email":      u.Email,
+		"first_name": u.FirstName,
+		"last_name":  u.LastName,
+	}
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			return NewFieldError(ErrInvalidArgument, field, "unknown field")
+		}
+		if utf8.RuneCountInString(value) > maxNameLen {
+			return NewFieldError(ErrInvalidArgument, field, fmt.Sprintf("must be at most %d characters", maxNameLen))
+		}
+	}
+
+	if slices.Contains(fields, "nickname") && u.Nickname == "" {
+		return NewFieldError(ErrInvalidArgument, "nickname", "nickname must not be empty")
+	}
+	if slices.Contains(fields, "email") {
+		if u.Email == "" {
+			return NewFieldError(ErrInvalidArgument, "email", "email must not be empty")
+		}
+		if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+			return NewFieldError(ErrInvalidArgument, "email", "malformed email")
+		}
+	}
+	return nil
+}
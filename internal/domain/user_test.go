@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUserValidate(t *testing.T) {
+	valid := User{Nickname: "john", Email: "john@example.com"}
+	tests := []struct {
+		name      string
+		user      User
+		fields    []string
+		wantField string
+	}{
+		{name: "Valid", user: valid},
+		{name: "Empty nickname", user: User{Email: "john@example.com"}, wantField: "nickname"},
+		{name: "Empty email", user: User{Nickname: "john"}, wantField: "email"},
+		{name: "Malformed email", user: User{Nickname: "john", Email: "John <john@example.com>"}, wantField: "email"},
+		{name: "Long last name", user: User{Nickname: "john", Email: "john@example.com", LastName: strings.Repeat("a", maxNameLen+1)}, wantField: "last_name"},
+		{name: "Only listed fields", user: User{FirstName: "John"}, fields: []string{"first_name"}},
+		{name: "Unknown field", user: valid, fields: []string{"password"}, wantField: "password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.user.Validate(tt.fields...)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField || !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("Validate() error = %v, want invalid %s", err, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeliveryState int
+
+const (
+	DeliveryPending DeliveryState = iota + 1
+	DeliveryDelivered
+	// DeliveryDeadLetter deliveries failed every attempt and are not retried.
+	DeliveryDeadLetter
+)
+
+// Webhook is an endpoint receiving user events. An empty EventTypes
+// subscribes it to every type.
+type Webhook struct {
+	ID         uuid.UUID
+	URL        string
+	EventTypes []EventType
+	CreatedAt  time.Time
+}
+
+// WebhookEvent is an event queued for the webhooks subscribed to its type.
+type WebhookEvent struct {
+	Offset int64
+	Type   EventType
+	// Payload is the body sent to the webhooks.
+	Payload []byte
+}
+
+// PendingDelivery is a webhook delivery claimed for an attempt.
+type PendingDelivery struct {
+	ID     int64
+	URL    string
+	Secret string
+	// Attempts is the number of attempts made before this one.
+	Attempts int
+	Payload  []byte
+}
+
+// DeliveryAttempt is the outcome of an attempt to deliver a webhook.
+type DeliveryAttempt struct {
+	DeliveryID int64
+	At         time.Time
+	// StatusCode is 0 if no response was received.
+	StatusCode int
+	Error      string
+	State      DeliveryState
+	// NextAttemptAt is when a delivery left DeliveryPending is retried.
+	NextAttemptAt time.Time
+}
+
+// DeliveryFilter restricts the deliveries returned by
+// ListWebhookDeliveries. Zero values are ignored.
+type DeliveryFilter struct {
+	WebhookID uuid.UUID
+	States    []DeliveryState
+}
+
+// ListDeliveriesQuery describes a page of webhook deliveries, newest first.
+type ListDeliveriesQuery struct {
+	Filter    DeliveryFilter
+	PageSize  int
+	PageToken string
+}
+
+// WebhookDelivery is a delivery of an event to a webhook along with the
+// outcome of its last attempt.
+type WebhookDelivery struct {
+	ID        int64
+	WebhookID uuid.UUID
+	Payload   []byte
+	State     DeliveryState
+	Attempts  int
+	CreatedAt time.Time
+	// NextAttemptAt is set for DeliveryPending deliveries.
+	NextAttemptAt time.Time
+	// LastAttemptAt is zero until the first attempt.
+	LastAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+}
+
+type DeliveryPage struct {
+	Deliveries    []*WebhookDelivery
+	NextPageToken string
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+// Broker is an in-process Publisher fanning events out to subscribers.
+// Publishing never blocks: a subscriber that falls behind misses events and
+// is expected to catch up by offset from the outbox.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[*Subscription]struct{}{}}
+}
+
+type Subscription struct {
+	C <-chan *domain.UserEvent
+
+	c      chan *domain.UserEvent
+	broker *Broker
+}
+
+// Subscribe returns a subscription buffering up to size events. It has to be
+// closed when no longer used.
+func (b *Broker) Subscribe(size int) *Subscription {
+	c := make(chan *domain.UserEvent, size)
+	sub := &Subscription{C: c, c: c, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	delete(s.broker.subs, s)
+}
+
+func (b *Broker) Publish(ctx context.Context, events []*domain.UserEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		for _, ev := range events {
+			select {
+			case sub.c <- ev:
+			default:
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package encode is the wire form of users and user events: the messages of
+// user_service.proto. It is shared by the API and by the publishers of user
+// events, so that consumers of either see the same messages.
+package encode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// User returns the UserInfo shown to clients. The version of the user is
+// sent as its etag.
+func User(u *domain.User) *proto.UserInfo {
+	if u == nil {
+		return nil
+	}
+	return &proto.UserInfo{
+		Oid:            UUID(u.Oid),
+		Nickname:       u.Nickname,
+		Email:          u.Email,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Etag:           Etag(u.Version),
+		State:          State(u.State),
+		StateReason:    u.StateReason,
+		SuspendedUntil: timestamp(u.SuspendedUntil),
+		DeletedAt:      timestamp(u.DeletedAt),
+	}
+}
+
+func UUID(id uuid.UUID) *proto.UUID {
+	return &proto.UUID{Value: id.String()}
+}
+
+// Etag returns the etag of a user with the version. Users not stored yet
+// have none.
+func Etag(version int64) string {
+	if version == 0 {
+		return ""
+	}
+	return strconv.FormatInt(version, 10)
+}
+
+// ParseEtag returns the version encoded in etag. An empty etag means the
+// caller did not ask for a precondition and yields 0.
+func ParseEtag(etag string) (int64, error) {
+	if etag == "" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(etag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors.New("malformed etag")
+	}
+	return version, nil
+}
+
+func State(state domain.State) proto.UserState {
+	switch state {
+	case domain.Active:
+		return proto.UserState_USER_STATE_ACTIVE
+	case domain.Banned:
+		return proto.UserState_USER_STATE_BANNED
+	case domain.Deleted:
+		return proto.UserState_USER_STATE_DELETED
+	case domain.Pending:
+		return proto.UserState_USER_STATE_PENDING
+	case domain.Suspended:
+		return proto.UserState_USER_STATE_SUSPENDED
+	}
+	return proto.UserState_USER_STATE_UNSPECIFIED
+}
+
+func StateFromProto(st proto.UserState) (domain.State, error) {
+	switch st {
+	case proto.UserState_USER_STATE_ACTIVE:
+		return domain.Active, nil
+	case proto.UserState_USER_STATE_BANNED:
+		return domain.Banned, nil
+	case proto.UserState_USER_STATE_DELETED:
+		return domain.Deleted, nil
+	case proto.UserState_USER_STATE_PENDING:
+		return domain.Pending, nil
+	case proto.UserState_USER_STATE_SUSPENDED:
+		return domain.Suspended, nil
+	}
+	return 0, fmt.Errorf("unknown user state %v", st)
+}
+
+func UserEvent(ev *domain.UserEvent) *proto.UserEvent {
+	return &proto.UserEvent{
+		Offset:     ev.Offset,
+		Type:       EventType(ev.Type),
+		OccurredAt: timestamp(ev.OccurredAt),
+		User:       User(ev.User),
+	}
+}
+
+func EventType(t domain.EventType) proto.UserEventType {
+	switch t {
+	case domain.EventCreated:
+		return proto.UserEventType_USER_EVENT_TYPE_CREATED
+	case domain.EventUpdated:
+		return proto.UserEventType_USER_EVENT_TYPE_UPDATED
+	case domain.EventDeleted:
+		return proto.UserEventType_USER_EVENT_TYPE_DELETED
+	}
+	return proto.UserEventType_USER_EVENT_TYPE_UNSPECIFIED
+}
+
+func EventTypeFromProto(t proto.UserEventType) (domain.EventType, error) {
+	switch t {
+	case proto.UserEventType_USER_EVENT_TYPE_CREATED:
+		return domain.EventCreated, nil
+	case proto.UserEventType_USER_EVENT_TYPE_UPDATED:
+		return domain.EventUpdated, nil
+	case proto.UserEventType_USER_EVENT_TYPE_DELETED:
+		return domain.EventDeleted, nil
+	}
+	return 0, fmt.Errorf("unknown event type %v", t)
+}
+
+// Marshal returns the JSON encoded UserEvent of ev, the body of messages
+// published to brokers and webhooks.
+func Marshal(ev *domain.UserEvent) ([]byte, error) {
+	return protojson.Marshal(UserEvent(ev))
+}
+
+// Unmarshal decodes an event encoded by Marshal.
+func Unmarshal(data []byte) (*proto.UserEvent, error) {
+	ev := &proto.UserEvent{}
+	if err := protojson.Unmarshal(data, ev); err != nil {
+		return nil, fmt.Errorf("unable to decode user event: %w", err)
+	}
+	return ev, nil
+}
+
+// timestamp leaves zero times unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,94 @@
+package encode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestUser(t *testing.T) {
+	oid := uuid.MustParse("e93b6308-fbc2-40a7-90fc-84627f1580dd")
+	until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := &domain.User{
+		Oid:            oid,
+		Nickname:       "john",
+		Email:          "john@example.com",
+		PasswordHash:   "hash",
+		State:          domain.Suspended,
+		StateReason:    "spam",
+		SuspendedUntil: until,
+		CreatedAt:      until.Add(-time.Hour),
+		Version:        4,
+	}
+	want := &proto.UserInfo{
+		Oid:            &proto.UUID{Value: oid.String()},
+		Nickname:       "john",
+		Email:          "john@example.com",
+		Etag:           "4",
+		State:          proto.UserState_USER_STATE_SUSPENDED,
+		StateReason:    "spam",
+		SuspendedUntil: timestamppb.New(until),
+	}
+
+	if got := User(user); !gproto.Equal(got, want) {
+		t.Errorf("User() = %v, want %v", got, want)
+	}
+}
+
+func TestParseEtag(t *testing.T) {
+	tests := []struct {
+		etag    string
+		want    int64
+		wantErr bool
+	}{
+		{etag: "", want: 0},
+		{etag: Etag(7), want: 7},
+		{etag: "0", wantErr: true},
+		{etag: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEtag(tt.etag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEtag(%q) error = %v, wantErr %v", tt.etag, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEtag(%q) = %d, want %d", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	for _, state := range []domain.State{domain.Deleted, domain.Banned, domain.Active, domain.Pending, domain.Suspended} {
+		got, err := StateFromProto(State(state))
+		if err != nil || got != state {
+			t.Errorf("StateFromProto(State(%v)) = %v, %v", state, got, err)
+		}
+	}
+	if _, err := StateFromProto(proto.UserState_USER_STATE_UNSPECIFIED); err == nil {
+		t.Errorf("StateFromProto() expected error for unspecified state")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ev := &domain.UserEvent{Offset: 3, Type: domain.EventDeleted, User: &domain.User{Nickname: "john"}}
+	data, err := Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !gproto.Equal(got, UserEvent(ev)) {
+		t.Errorf("Unmarshal() = %v, want %v", got, UserEvent(ev))
+	}
+	if _, err := Unmarshal([]byte("not json")); err == nil {
+		t.Errorf("Unmarshal() expected error for malformed payload")
+	}
+}
@@ -0,0 +1,89 @@
+// Package events publishes user lifecycle events written to the outbox.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+)
+
+// Publisher delivers events to consumers, in the order given. Production
+// deployments plug in their message broker; FilePublisher is a local stand-in
+// writing the same messages a NATS publisher would.
+type Publisher interface {
+	Publish(ctx context.Context, events []*domain.UserEvent) error
+}
+
+// Multi publishes events through each publisher in turn and stops at the
+// first failure.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, events []*domain.UserEvent) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subject returns the NATS subject of events of the type, e.g.
+// "users.created".
+func Subject(t domain.EventType) string {
+	return "users." + t.String()
+}
+
+// FilePublisher appends events as JSON lines to a file, one message per
+// event with its subject and the event encoded as protobuf JSON.
+type FilePublisher struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, events []*domain.UserEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open events file: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, ev := range events {
+		data, err := encode.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("unable to encode event: %w", err)
+		}
+		msg := struct {
+			Subject string          `json:"subject"`
+			Data    json.RawMessage `json:"data"`
+		}{Subject(ev.Type), data}
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("unable to write event: %w", err)
+		}
+	}
+	return nil
+}
+
+// New returns the publisher selected by kind, "none" or "file". The
+// in-process Broker is not selected here, it is always used.
+func New(kind, path string) (Publisher, error) {
+	switch kind {
+	case "", "none":
+		return Multi{}, nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file publisher requires a path")
+		}
+		return &FilePublisher{Path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown publisher %q", kind)
+}
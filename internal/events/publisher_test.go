@@ -0,0 +1,98 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	p, err := New("file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []*domain.UserEvent{
+		{Offset: 1, Type: domain.EventCreated, User: &domain.User{Nickname: "john"}},
+		{Offset: 2, Type: domain.EventDeleted, User: &domain.User{Nickname: "john"}},
+	}
+	if err := p.Publish(context.Background(), events); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	wantSubjects := []string{"users.created", "users.deleted"}
+	var i int
+	sc := bufio.NewScanner(f)
+	for ; sc.Scan(); i++ {
+		var msg struct {
+			Subject string          `json:"subject"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		ev := &proto.UserEvent{}
+		if err := protojson.Unmarshal(msg.Data, ev); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != wantSubjects[i] || ev.Offset != events[i].Offset || ev.GetUser().GetNickname() != "john" {
+			t.Errorf("message %d = %s offset %d, want %s offset %d", i, msg.Subject, ev.Offset, wantSubjects[i], events[i].Offset)
+		}
+	}
+	if i != len(events) {
+		t.Errorf("file contains %d messages, want %d", i, len(events))
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("file", ""); err == nil {
+		t.Errorf("New() expected error for file publisher without path")
+	}
+	if _, err := New("kafka", ""); err == nil {
+		t.Errorf("New() expected error for unknown publisher")
+	}
+	if p, err := New("", ""); err != nil || p == nil {
+		t.Errorf("New() = %v, %v, want no-op publisher", p, err)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := NewBroker()
+	sub := b.Subscribe(1)
+	closed := b.Subscribe(1)
+	closed.Close()
+
+	events := []*domain.UserEvent{{Offset: 1}, {Offset: 2}}
+	if err := b.Publish(context.Background(), events); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// the buffer holds one event, the second one is dropped
+	if ev := <-sub.C; ev.Offset != 1 {
+		t.Errorf("received offset %d, want 1", ev.Offset)
+	}
+	select {
+	case ev := <-sub.C:
+		t.Errorf("received offset %d, want nothing", ev.Offset)
+	default:
+	}
+	select {
+	case <-closed.C:
+		t.Errorf("closed subscription received an event")
+	default:
+	}
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+)
+
+const defaultBatchSize = 100
+
+// Relay moves events from the outbox to a Publisher. Events are marked
+// published only after Publish succeeded, so they are delivered at least
+// once; consumers drop duplicates by offset.
+type Relay struct {
+	DB        domain.DomainInterface
+	Publisher Publisher
+	// BatchSize is how many events are published at once, 100 if zero.
+	BatchSize int
+}
+
+// Run publishes pending events until the outbox is drained. It is meant to
+// be called periodically, e.g. by jobs.Run.
+func (r *Relay) Run(ctx context.Context) error {
+	size := r.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+
+	for {
+		pending, err := r.DB.PendingUserEvents(ctx, size)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if err := r.Publisher.Publish(ctx, pending); err != nil {
+			return fmt.Errorf("unable to publish events: %w", err)
+		}
+		if err := r.DB.MarkUserEventsPublished(ctx, pending[len(pending)-1].Offset); err != nil {
+			return err
+		}
+		if len(pending) < size {
+			return nil
+		}
+	}
+}
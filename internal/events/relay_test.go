@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakePublisher struct {
+	published []*domain.UserEvent
+	err       error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, events []*domain.UserEvent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, events...)
+	return nil
+}
+
+func TestRelay_Run(t *testing.T) {
+	first := []*domain.UserEvent{{Offset: 1}, {Offset: 2}}
+	second := []*domain.UserEvent{{Offset: 5}}
+
+	tests := []struct {
+		name       string
+		publishErr error
+		wantErr    bool
+		wantCount  int
+	}{
+		{name: "Positive case", wantCount: 3},
+		{name: "Publish error", publishErr: errors.New("broker down"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := mocks.NewDomainInterface(t)
+			pub := &fakePublisher{err: tt.publishErr}
+			r := &Relay{DB: mockDB, Publisher: pub, BatchSize: 2}
+
+			mockDB.On("PendingUserEvents", mock.Anything, 2).Return(first, nil).Once()
+			if !tt.wantErr {
+				mockDB.On("MarkUserEventsPublished", mock.Anything, int64(2)).Return(nil).Once()
+				mockDB.On("PendingUserEvents", mock.Anything, 2).Return(second, nil).Once()
+				mockDB.On("MarkUserEventsPublished", mock.Anything, int64(5)).Return(nil).Once()
+			}
+
+			err := r.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Relay.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(pub.published) != tt.wantCount {
+				t.Errorf("Relay.Run() published %d events, want %d", len(pub.published), tt.wantCount)
+			}
+		})
+	}
+}
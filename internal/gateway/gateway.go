@@ -0,0 +1,109 @@
+// Package gateway serves UserService as REST/JSON, transcoding requests to
+// gRPC calls following the google.api.http rules of user_service.proto.
+//
+// Errors are answered with the JSON form of the gRPC status and the HTTP
+// status matching its code: INVALID_ARGUMENT and FAILED_PRECONDITION are 400,
+// UNAUTHENTICATED 401, PERMISSION_DENIED 403, NOT_FOUND 404, ALREADY_EXISTS
+// and ABORTED 409, UNAVAILABLE 503.
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/sosshik/grpc-user-managment/internal/clientaddr"
+	"github.com/sosshik/grpc-user-managment/internal/requestid"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// forwardedFor is the metadata key the gateway puts the address of the HTTP
+// client in, after any addresses sent by the client itself.
+const forwardedFor = "x-forwarded-for"
+
+var requestIDHeader = textproto.CanonicalMIMEHeaderKey(requestid.MetadataKey)
+
+// New returns a handler calling the UserService behind conn.
+func New(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithForwardResponseOption(setStatus),
+	)
+	if err := proto.RegisterUserServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// incomingHeader passes the request id on, the Authorization header is
+// always passed by the gateway.
+func incomingHeader(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == requestIDHeader {
+		return requestid.MetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+func outgoingHeader(key string) (string, bool) {
+	if key == requestid.MetadataKey {
+		return requestIDHeader, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// setStatus answers calls creating a resource with 201 Created.
+func setStatus(_ context.Context, w http.ResponseWriter, resp protobuf.Message) error {
+	switch resp.(type) {
+	case *proto.CreateUserResponse, *proto.CreateWebhookResponse:
+		w.WriteHeader(http.StatusCreated)
+	}
+	return nil
+}
+
+// PeerAddr returns the address of the caller of the RPC in ctx. For calls
+// made through the gateway it is the address of the HTTP client, which only
+// the gateway can vouch for.
+func PeerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if p.Addr.Network() != Network {
+		return p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	fwd := md.Get(forwardedFor)
+	if len(fwd) == 0 {
+		return p.Addr.String()
+	}
+	addrs := strings.Split(fwd[len(fwd)-1], ",")
+	return strings.TrimSpace(addrs[len(addrs)-1])
+}
+
+// UnaryInterceptor stores the address returned by PeerAddr in the context
+// of the call, where clientaddr.FromContext finds it.
+func UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(clientaddr.NewContext(ctx, PeerAddr(ctx)), req)
+}
+
+// StreamInterceptor is the streaming counterpart of UnaryInterceptor.
+func StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := clientaddr.NewContext(ss.Context(), PeerAddr(ss.Context()))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
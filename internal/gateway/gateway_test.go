@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sosshik/grpc-user-managment/internal/clientaddr"
+	"github.com/sosshik/grpc-user-managment/internal/requestid"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServer struct {
+	proto.UnimplementedUserServiceServer
+	authorization string
+	peer          string
+}
+
+func (s *fakeServer) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.CreateUserResponse, error) {
+	return &proto.CreateUserResponse{Oid: &proto.UUID{Value: "oid-1"}}, nil
+}
+
+func (s *fakeServer) GetUserByID(ctx context.Context, req *proto.GetUserByIDRequest) (*proto.GetUserByIDResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.authorization = strings.Join(md.Get("authorization"), ",")
+	s.peer = PeerAddr(ctx)
+	if req.GetOid().GetValue() != "oid-1" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &proto.GetUserByIDResponse{User: &proto.UserInfo{Oid: req.GetOid()}}, nil
+}
+
+func (s *fakeServer) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UpdateUserResponse, error) {
+	return nil, status.Error(codes.Aborted, "etag mismatch")
+}
+
+func newTestGateway(t *testing.T) (*fakeServer, *httptest.Server) {
+	t.Helper()
+	fake := &fakeServer{}
+	s := grpc.NewServer(grpc.UnaryInterceptor(requestid.UnaryInterceptor))
+	proto.RegisterUserServiceServer(s, fake)
+	pipe := NewPipeListener()
+	go s.Serve(pipe)
+	t.Cleanup(s.Stop)
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, Network,
+		grpc.WithContextDialer(pipe.Dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	handler, err := New(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func TestGateway(t *testing.T) {
+	_, srv := newTestGateway(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "Create", method: http.MethodPost, path: "/v1/users", body: `{"password":"secret"}`, wantStatus: http.StatusCreated},
+		{name: "Get", method: http.MethodGet, path: "/v1/users/oid-1", wantStatus: http.StatusOK},
+		{name: "Not found", method: http.MethodGet, path: "/v1/users/oid-2", wantStatus: http.StatusNotFound},
+		{name: "Conflict", method: http.MethodPatch, path: "/v1/users/oid-1", body: `{}`, wantStatus: http.StatusConflict},
+		{name: "Unimplemented", method: http.MethodGet, path: "/v1/webhooks", wantStatus: http.StatusNotImplemented},
+		{name: "Bad body", method: http.MethodPost, path: "/v1/auth/login", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "Unknown route", method: http.MethodGet, path: "/v1/nothing", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestGatewayHeaders(t *testing.T) {
+	fake, srv := newTestGateway(t)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/users/oid-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"))
+	assert.Equal(t, "Bearer abc", fake.authorization)
+	// The address sent by the client is not trusted.
+	assert.Equal(t, "127.0.0.1", fake.peer)
+}
+
+func TestPeerAddr(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+	tests := []struct {
+		name string
+		addr net.Addr
+		md   metadata.MD
+		want string
+	}{
+		{name: "Direct call", addr: remote, want: "192.0.2.1:5000"},
+		{name: "Direct call ignores forwarded", addr: remote, md: metadata.Pairs(forwardedFor, "10.0.0.1"), want: "192.0.2.1:5000"},
+		{name: "Gateway call", addr: pipeAddr{}, md: metadata.Pairs(forwardedFor, "10.0.0.1, 198.51.100.7"), want: "198.51.100.7"},
+		{name: "Gateway call without address", addr: pipeAddr{}, want: Network},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			assert.Equal(t, tt.want, PeerAddr(ctx))
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: pipeAddr{}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedFor, "198.51.100.7"))
+
+	var got string
+	_, err := UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		got = clientaddr.FromContext(ctx)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "198.51.100.7", got)
+}
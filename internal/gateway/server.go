@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Server serves UserService as REST/JSON. It reaches the service through a
+// gRPC server of its own listening on a PipeListener. Calls never leave the
+// process, so that server has no transport credentials and the gateway works
+// whether or not the public gRPC server uses TLS.
+type Server struct {
+	http *http.Server
+	grpc *grpc.Server
+	conn *grpc.ClientConn
+}
+
+// NewServer returns a Server calling service. opts are the options of its
+// gRPC server and should carry the interceptors of the public one, so that
+// HTTP calls are authenticated the same way.
+func NewServer(ctx context.Context, service proto.UserServiceServer, opts ...grpc.ServerOption) (*Server, error) {
+	s := grpc.NewServer(opts...)
+	proto.RegisterUserServiceServer(s, service)
+	pipe := NewPipeListener()
+	go s.Serve(pipe)
+
+	conn, err := grpc.DialContext(ctx, Network,
+		grpc.WithContextDialer(pipe.Dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		s.Stop()
+		return nil, fmt.Errorf("unable to connect the gateway: %w", err)
+	}
+	handler, err := New(ctx, conn)
+	if err != nil {
+		conn.Close()
+		s.Stop()
+		return nil, fmt.Errorf("unable to create the gateway: %w", err)
+	}
+
+	return &Server{
+		http: &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+		grpc: s,
+		conn: conn,
+	}, nil
+}
+
+// Serve answers HTTP requests on l until Shutdown is called.
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.http.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops taking requests and waits for the running ones until ctx is
+// done, then closes the gRPC server behind the gateway.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+	s.conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+	return err
+}
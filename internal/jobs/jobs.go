@@ -0,0 +1,28 @@
+// Package jobs runs periodic background maintenance tasks.
+package jobs
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Run calls fn every interval until ctx is done. Failures are logged and do
+// not stop the job. Run blocks, so it is usually started in a goroutine.
+func Run(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil {
+			log.Warnf("%s: %s", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,10 +3,13 @@
 package mocks
 
 import (
+	context "context"
+
 	domain "github.com/sosshik/grpc-user-managment/internal/domain"
+
 	mock "github.com/stretchr/testify/mock"
 
-	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	time "time"
 
 	uuid "github.com/google/uuid"
 )
@@ -16,13 +19,93 @@ type DomainInterface struct {
 	mock.Mock
 }
 
-// CreateUser provides a mock function with given fields: user, pass, state
-func (_m *DomainInterface) CreateUser(user *proto.UserInfo, pass string, state domain.State) error {
-	ret := _m.Called(user, pass, state)
+// ChangeState provides a mock function with given fields: ctx, oid, change
+func (_m *DomainInterface) ChangeState(ctx context.Context, oid uuid.UUID, change domain.StateChange) (*domain.User, error) {
+	ret := _m.Called(ctx, oid, change)
+
+	var r0 *domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, domain.StateChange) (*domain.User, error)); ok {
+		return rf(ctx, oid, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, domain.StateChange) *domain.User); ok {
+		r0 = rf(ctx, oid, change)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, domain.StateChange) error); ok {
+		r1 = rf(ctx, oid, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimWebhookDeliveries provides a mock function with given fields: ctx, now, lease, limit
+func (_m *DomainInterface) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.PendingDelivery, error) {
+	ret := _m.Called(ctx, now, lease, limit)
+
+	var r0 []*domain.PendingDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) ([]*domain.PendingDelivery, error)); ok {
+		return rf(ctx, now, lease, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) []*domain.PendingDelivery); ok {
+		r0 = rf(ctx, now, lease, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.PendingDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int) error); ok {
+		r1 = rf(ctx, now, lease, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateRefreshToken provides a mock function with given fields: ctx, token
+func (_m *DomainInterface) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	ret := _m.Called(ctx, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.RefreshToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateResetToken provides a mock function with given fields: ctx, oid, hash, expiresAt
+func (_m *DomainInterface) CreateResetToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error {
+	ret := _m.Called(ctx, oid, hash, expiresAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Time) error); ok {
+		r0 = rf(ctx, oid, hash, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *DomainInterface) CreateUser(ctx context.Context, user *domain.User) error {
+	ret := _m.Called(ctx, user)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*proto.UserInfo, string, domain.State) error); ok {
-		r0 = rf(user, pass, state)
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User) error); ok {
+		r0 = rf(ctx, user)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -30,13 +113,261 @@ func (_m *DomainInterface) CreateUser(user *proto.UserInfo, pass string, state d
 	return r0
 }
 
-// DeleteUser provides a mock function with given fields: oid
-func (_m *DomainInterface) DeleteUser(oid uuid.UUID) error {
-	ret := _m.Called(oid)
+// CreateVerificationToken provides a mock function with given fields: ctx, oid, hash, expiresAt
+func (_m *DomainInterface) CreateVerificationToken(ctx context.Context, oid uuid.UUID, hash string, expiresAt time.Time) error {
+	ret := _m.Called(ctx, oid, hash, expiresAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Time) error); ok {
+		r0 = rf(ctx, oid, hash, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateWebhook provides a mock function with given fields: ctx, webhook, secret
+func (_m *DomainInterface) CreateWebhook(ctx context.Context, webhook *domain.Webhook, secret string) error {
+	ret := _m.Called(ctx, webhook, secret)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Webhook, string) error); ok {
+		r0 = rf(ctx, webhook, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUnverifiedUsers provides a mock function with given fields: ctx, createdBefore
+func (_m *DomainInterface) DeleteUnverifiedUsers(ctx context.Context, createdBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, createdBefore)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, createdBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, createdBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, createdBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteUser provides a mock function with given fields: ctx, oid, version
+func (_m *DomainInterface) DeleteUser(ctx context.Context, oid uuid.UUID, version int64) error {
+	ret := _m.Called(ctx, oid, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, int64) error); ok {
+		r0 = rf(ctx, oid, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteWebhook provides a mock function with given fields: ctx, id
+func (_m *DomainInterface) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnqueueWebhookDeliveries provides a mock function with given fields: ctx, events
+func (_m *DomainInterface) EnqueueWebhookDeliveries(ctx context.Context, events []*domain.WebhookEvent) error {
+	ret := _m.Called(ctx, events)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*domain.WebhookEvent) error); ok {
+		r0 = rf(ctx, events)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FirstUserEventOffset provides a mock function with given fields: ctx
+func (_m *DomainInterface) FirstUserEventOffset(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCredentials provides a mock function with given fields: ctx, email, nickname
+func (_m *DomainInterface) GetCredentials(ctx context.Context, email string, nickname string) (*domain.Credentials, error) {
+	ret := _m.Called(ctx, email, nickname)
+
+	var r0 *domain.Credentials
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*domain.Credentials, error)); ok {
+		return rf(ctx, email, nickname)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.Credentials); ok {
+		r0 = rf(ctx, email, nickname)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Credentials)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, nickname)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCredentialsByID provides a mock function with given fields: ctx, oid
+func (_m *DomainInterface) GetCredentialsByID(ctx context.Context, oid uuid.UUID) (*domain.Credentials, error) {
+	ret := _m.Called(ctx, oid)
+
+	var r0 *domain.Credentials
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*domain.Credentials, error)); ok {
+		return rf(ctx, oid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *domain.Credentials); ok {
+		r0 = rf(ctx, oid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Credentials)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, oid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRoles provides a mock function with given fields: ctx, oid
+func (_m *DomainInterface) GetRoles(ctx context.Context, oid uuid.UUID) ([]domain.Role, error) {
+	ret := _m.Called(ctx, oid)
+
+	var r0 []domain.Role
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]domain.Role, error)); ok {
+		return rf(ctx, oid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []domain.Role); ok {
+		r0 = rf(ctx, oid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, oid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByEmail provides a mock function with given fields: ctx, email, includeDeleted
+func (_m *DomainInterface) GetUserByEmail(ctx context.Context, email string, includeDeleted bool) (*domain.User, error) {
+	ret := _m.Called(ctx, email, includeDeleted)
+
+	var r0 *domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) (*domain.User, error)); ok {
+		return rf(ctx, email, includeDeleted)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *domain.User); ok {
+		r0 = rf(ctx, email, includeDeleted)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, email, includeDeleted)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByID provides a mock function with given fields: ctx, oid, includeDeleted
+func (_m *DomainInterface) GetUserByID(ctx context.Context, oid uuid.UUID, includeDeleted bool) (*domain.User, error) {
+	ret := _m.Called(ctx, oid, includeDeleted)
+
+	var r0 *domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, bool) (*domain.User, error)); ok {
+		return rf(ctx, oid, includeDeleted)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, bool) *domain.User); ok {
+		r0 = rf(ctx, oid, includeDeleted)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, bool) error); ok {
+		r1 = rf(ctx, oid, includeDeleted)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GrantRole provides a mock function with given fields: ctx, oid, role
+func (_m *DomainInterface) GrantRole(ctx context.Context, oid uuid.UUID, role domain.Role) error {
+	ret := _m.Called(ctx, oid, role)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
-		r0 = rf(oid)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, domain.Role) error); ok {
+		r0 = rf(ctx, oid, role)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -44,25 +375,127 @@ func (_m *DomainInterface) DeleteUser(oid uuid.UUID) error {
 	return r0
 }
 
-// GetUserByEmail provides a mock function with given fields: email
-func (_m *DomainInterface) GetUserByEmail(email string) (*proto.UserInfo, error) {
-	ret := _m.Called(email)
+// LiftSuspensions provides a mock function with given fields: ctx, now
+func (_m *DomainInterface) LiftSuspensions(ctx context.Context, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAuditEvents provides a mock function with given fields: ctx, query
+func (_m *DomainInterface) ListAuditEvents(ctx context.Context, query domain.ListAuditEventsQuery) (*domain.AuditPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 *domain.AuditPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListAuditEventsQuery) (*domain.AuditPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListAuditEventsQuery) *domain.AuditPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.AuditPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ListAuditEventsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUserEvents provides a mock function with given fields: ctx, afterOffset, limit
+func (_m *DomainInterface) ListUserEvents(ctx context.Context, afterOffset int64, limit int) ([]*domain.UserEvent, error) {
+	ret := _m.Called(ctx, afterOffset, limit)
+
+	var r0 []*domain.UserEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]*domain.UserEvent, error)); ok {
+		return rf(ctx, afterOffset, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []*domain.UserEvent); ok {
+		r0 = rf(ctx, afterOffset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.UserEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterOffset, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, query
+func (_m *DomainInterface) ListUsers(ctx context.Context, query domain.ListUsersQuery) (*domain.UserPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 *domain.UserPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListUsersQuery) (*domain.UserPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListUsersQuery) *domain.UserPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.UserPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ListUsersQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: ctx, query
+func (_m *DomainInterface) ListWebhookDeliveries(ctx context.Context, query domain.ListDeliveriesQuery) (*domain.DeliveryPage, error) {
+	ret := _m.Called(ctx, query)
 
-	var r0 *proto.UserInfo
+	var r0 *domain.DeliveryPage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (*proto.UserInfo, error)); ok {
-		return rf(email)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListDeliveriesQuery) (*domain.DeliveryPage, error)); ok {
+		return rf(ctx, query)
 	}
-	if rf, ok := ret.Get(0).(func(string) *proto.UserInfo); ok {
-		r0 = rf(email)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ListDeliveriesQuery) *domain.DeliveryPage); ok {
+		r0 = rf(ctx, query)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*proto.UserInfo)
+			r0 = ret.Get(0).(*domain.DeliveryPage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(email)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ListDeliveriesQuery) error); ok {
+		r1 = rf(ctx, query)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -70,25 +503,25 @@ func (_m *DomainInterface) GetUserByEmail(email string) (*proto.UserInfo, error)
 	return r0, r1
 }
 
-// GetUserByID provides a mock function with given fields: oid
-func (_m *DomainInterface) GetUserByID(oid uuid.UUID) (*proto.UserInfo, error) {
-	ret := _m.Called(oid)
+// ListWebhooks provides a mock function with given fields: ctx
+func (_m *DomainInterface) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	ret := _m.Called(ctx)
 
-	var r0 *proto.UserInfo
+	var r0 []*domain.Webhook
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uuid.UUID) (*proto.UserInfo, error)); ok {
-		return rf(oid)
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*domain.Webhook, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(uuid.UUID) *proto.UserInfo); ok {
-		r0 = rf(oid)
+	if rf, ok := ret.Get(0).(func(context.Context) []*domain.Webhook); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*proto.UserInfo)
+			r0 = ret.Get(0).([]*domain.Webhook)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
-		r1 = rf(oid)
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -96,25 +529,39 @@ func (_m *DomainInterface) GetUserByID(oid uuid.UUID) (*proto.UserInfo, error) {
 	return r0, r1
 }
 
-// GetUsers provides a mock function with given fields:
-func (_m *DomainInterface) GetUsers() ([]*proto.UserInfo, error) {
-	ret := _m.Called()
+// MarkUserEventsPublished provides a mock function with given fields: ctx, upTo
+func (_m *DomainInterface) MarkUserEventsPublished(ctx context.Context, upTo int64) error {
+	ret := _m.Called(ctx, upTo)
 
-	var r0 []*proto.UserInfo
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, upTo)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PendingUserEvents provides a mock function with given fields: ctx, limit
+func (_m *DomainInterface) PendingUserEvents(ctx context.Context, limit int) ([]*domain.UserEvent, error) {
+	ret := _m.Called(ctx, limit)
+
+	var r0 []*domain.UserEvent
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*proto.UserInfo, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*domain.UserEvent, error)); ok {
+		return rf(ctx, limit)
 	}
-	if rf, ok := ret.Get(0).(func() []*proto.UserInfo); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*domain.UserEvent); ok {
+		r0 = rf(ctx, limit)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*proto.UserInfo)
+			r0 = ret.Get(0).([]*domain.UserEvent)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -122,13 +569,61 @@ func (_m *DomainInterface) GetUsers() ([]*proto.UserInfo, error) {
 	return r0, r1
 }
 
-// UpdateUser provides a mock function with given fields: user
-func (_m *DomainInterface) UpdateUser(user *proto.UserInfo) error {
-	ret := _m.Called(user)
+// PurgeDeletedUsers provides a mock function with given fields: ctx, deletedBefore
+func (_m *DomainInterface) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, deletedBefore)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, deletedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, deletedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, deletedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeUserEvents provides a mock function with given fields: ctx, publishedBefore
+func (_m *DomainInterface) PurgeUserEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, publishedBefore)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, publishedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, publishedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, publishedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordDeliveryAttempt provides a mock function with given fields: ctx, attempt
+func (_m *DomainInterface) RecordDeliveryAttempt(ctx context.Context, attempt domain.DeliveryAttempt) error {
+	ret := _m.Called(ctx, attempt)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*proto.UserInfo) error); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.DeliveryAttempt) error); ok {
+		r0 = rf(ctx, attempt)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -136,6 +631,176 @@ func (_m *DomainInterface) UpdateUser(user *proto.UserInfo) error {
 	return r0
 }
 
+// ResetPassword provides a mock function with given fields: ctx, tokenHash, passwordHash
+func (_m *DomainInterface) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error) {
+	ret := _m.Called(ctx, tokenHash, passwordHash)
+
+	var r0 uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (uuid.UUID, error)); ok {
+		return rf(ctx, tokenHash, passwordHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) uuid.UUID); ok {
+		r0 = rf(ctx, tokenHash, passwordHash)
+	} else {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tokenHash, passwordHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreUser provides a mock function with given fields: ctx, oid
+func (_m *DomainInterface) RestoreUser(ctx context.Context, oid uuid.UUID) (*domain.User, error) {
+	ret := _m.Called(ctx, oid)
+
+	var r0 *domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*domain.User, error)); ok {
+		return rf(ctx, oid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *domain.User); ok {
+		r0 = rf(ctx, oid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, oid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeRefreshToken provides a mock function with given fields: ctx, hash
+func (_m *DomainInterface) RevokeRefreshToken(ctx context.Context, hash string) error {
+	ret := _m.Called(ctx, hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeRole provides a mock function with given fields: ctx, oid, role
+func (_m *DomainInterface) RevokeRole(ctx context.Context, oid uuid.UUID, role domain.Role) error {
+	ret := _m.Called(ctx, oid, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, domain.Role) error); ok {
+		r0 = rf(ctx, oid, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateRefreshToken provides a mock function with given fields: ctx, hash, next
+func (_m *DomainInterface) RotateRefreshToken(ctx context.Context, hash string, next *domain.RefreshToken) error {
+	ret := _m.Called(ctx, hash, next)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *domain.RefreshToken) error); ok {
+		r0 = rf(ctx, hash, next)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPassword provides a mock function with given fields: ctx, oid, hash
+func (_m *DomainInterface) SetPassword(ctx context.Context, oid uuid.UUID, hash string) error {
+	ret := _m.Called(ctx, oid, hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, oid, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamUsers provides a mock function with given fields: ctx, filter, fn
+func (_m *DomainInterface) StreamUsers(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserFilter, func(*domain.User) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user, fields
+func (_m *DomainInterface) UpdateUser(ctx context.Context, user *domain.User, fields []string) (*domain.User, error) {
+	ret := _m.Called(ctx, user, fields)
+
+	var r0 *domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User, []string) (*domain.User, error)); ok {
+		return rf(ctx, user, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.User, []string) *domain.User); ok {
+		r0 = rf(ctx, user, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.User, []string) error); ok {
+		r1 = rf(ctx, user, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, tokenHash
+func (_m *DomainInterface) VerifyEmail(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (uuid.UUID, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) uuid.UUID); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewDomainInterface creates a new instance of DomainInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDomainInterface(t interface {
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Message is a notification addressed to a user, e.g. an email.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to users. Production deployments plug in their
+// mailer; LogNotifier and FileNotifier are meant for local development and
+// tests.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes messages to the application log.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, msg Message) error {
+	log.Infof("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier appends messages as JSON lines to a file.
+type FileNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	line := struct {
+		Time time.Time `json:"time"`
+		Message
+	}{time.Now().UTC(), msg}
+	if err := json.NewEncoder(f).Encode(line); err != nil {
+		return fmt.Errorf("unable to write notification: %w", err)
+	}
+	return nil
+}
+
+// New returns the notifier selected by kind, "log" or "file".
+func New(kind, path string) (Notifier, error) {
+	switch kind {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file notifier requires a path")
+		}
+		return &FileNotifier{Path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q", kind)
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	n, err := New("file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := []Message{
+		{To: "a@example.com", Subject: "first", Body: "1"},
+		{To: "b@example.com", Subject: "second", Body: "2"},
+	}
+	for _, m := range msgs {
+		if err := n.Send(context.Background(), m); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []Message
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	if len(got) != len(msgs) || got[0] != msgs[0] || got[1] != msgs[1] {
+		t.Errorf("file contains %v, want %v", got, msgs)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("file", ""); err == nil {
+		t.Errorf("New() expected error for file notifier without path")
+	}
+	if _, err := New("pigeon", ""); err == nil {
+		t.Errorf("New() expected error for unknown notifier")
+	}
+	if n, err := New("", ""); err != nil || n == nil {
+		t.Errorf("New() = %v, %v, want log notifier", n, err)
+	}
+}
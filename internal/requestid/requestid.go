@@ -0,0 +1,61 @@
+// Package requestid tags every RPC with an id that shows up in logs and the
+// audit log. Callers may pass their own id in the "x-request-id" metadata,
+// otherwise one is generated. The id is sent back in the response header.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const MetadataKey = "x-request-id"
+
+// maxLength bounds ids sent by callers.
+const maxLength = 128
+
+type key struct{}
+
+// NewContext returns a copy of ctx carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext returns the request id of ctx, or an empty string outside of
+// an RPC.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+func UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if ids := md.Get(MetadataKey); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= maxLength {
+		id = ids[0]
+	} else {
+		id = uuid.NewString()
+	}
+	// Fails only outside of an RPC, where there is nobody to send it to.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+	return NewContext(ctx, id)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{name: "Id from caller", incoming: "req-1", wantSame: true},
+		{name: "Generated id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.incoming != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, tt.incoming))
+			}
+
+			var got string
+			_, err := UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				got = FromContext(ctx)
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == "" {
+				t.Fatal("request id is empty")
+			}
+			if (got == tt.incoming) != tt.wantSame {
+				t.Errorf("request id = %q, incoming %q", got, tt.incoming)
+			}
+		})
+	}
+}
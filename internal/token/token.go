@@ -0,0 +1,165 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Config struct {
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// SigningKey is the Ed25519 private key used to sign access tokens.
+	SigningKey ed25519.PrivateKey
+}
+
+// Claims are the claims of an access token. The subject is the user oid.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// Manager mints and verifies access tokens and generates refresh tokens.
+type Manager struct {
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	key        ed25519.PrivateKey
+	keyID      string
+}
+
+func NewManager(cfg Config) *Manager {
+	pub := cfg.SigningKey.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &Manager{
+		issuer:     cfg.Issuer,
+		accessTTL:  cfg.AccessTTL,
+		refreshTTL: cfg.RefreshTTL,
+		key:        cfg.SigningKey,
+		keyID:      base64.RawURLEncoding.EncodeToString(sum[:8]),
+	}
+}
+
+// ParseSigningKey decodes a base64 encoded Ed25519 seed or private key.
+func ParseSigningKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signing key: %w", err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	}
+	return nil, fmt.Errorf("signing key must be %d or %d bytes long, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(b))
+}
+
+// GenerateSigningKey returns a random Ed25519 key.
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// IssueAccessToken returns a signed access token for the user and its
+// expiration time.
+func (m *Manager) IssueAccessToken(oid uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(m.accessTTL)
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   oid.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+			ID:        uuid.New().String(),
+		},
+	})
+	t.Header["kid"] = m.keyID
+
+	signed, err := t.SignedString(m.key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("unable to sign access token: %w", err)
+	}
+	return signed, expires, nil
+}
+
+// VerifyAccessToken checks the signature, issuer and lifetime of an access
+// token and returns its claims.
+func (m *Manager) VerifyAccessToken(s string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(s, claims, func(t *jwt.Token) (any, error) {
+		return m.key.Public(), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token, the hash under
+// which it is stored and its expiration time.
+func (m *Manager) NewRefreshToken() (token, hash string, expires time.Time, err error) {
+	token, hash, err = NewOpaque()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("unable to generate refresh token: %w", err)
+	}
+	return token, hash, time.Now().Add(m.refreshTTL), nil
+}
+
+// NewOpaque returns a random single-use token, such as a refresh or password
+// reset token, together with its hash.
+func NewOpaque() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the value stored for an opaque token. Only hashes are
+// persisted, so a database leak doesn't expose usable tokens.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// PublicKeys returns the key set downstream services use to verify access
+// tokens offline.
+func (m *Manager) PublicKeys() []JWK {
+	pub := m.key.Public().(ed25519.PublicKey)
+	return []JWK{{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(pub),
+		Kid: m.keyID,
+		Alg: jwt.SigningMethodEdDSA.Alg(),
+		Use: "sig",
+	}}
+}
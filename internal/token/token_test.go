@@ -0,0 +1,112 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestManager(t *testing.T, accessTTL time.Duration) *Manager {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(Config{Issuer: "test", AccessTTL: accessTTL, RefreshTTL: time.Hour, SigningKey: key})
+}
+
+func TestManager_AccessToken(t *testing.T) {
+	m := newTestManager(t, time.Minute)
+	other := newTestManager(t, time.Minute)
+	expired := NewManager(Config{Issuer: "test", AccessTTL: -time.Minute, SigningKey: m.key})
+	oid := uuid.New()
+
+	valid, _, err := m.IssueAccessToken(oid)
+	if err != nil {
+		t.Fatalf("IssueAccessToken() error = %v", err)
+	}
+	foreign, _, _ := other.IssueAccessToken(oid)
+	stale, _, _ := expired.IssueAccessToken(oid)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "Valid", token: valid},
+		{name: "Signed by another key", token: foreign, wantErr: true},
+		{name: "Expired", token: stale, wantErr: true},
+		{name: "Tampered", token: valid[:strings.LastIndex(valid, ".")] + ".AAAA", wantErr: true},
+		{name: "Garbage", token: "not a token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := m.VerifyAccessToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+			}
+			if err == nil && claims.Subject != oid.String() {
+				t.Errorf("VerifyAccessToken() subject = %v, want %v", claims.Subject, oid)
+			}
+		})
+	}
+}
+
+func TestManager_PublicKeys(t *testing.T) {
+	m := newTestManager(t, time.Minute)
+	signed, _, _ := m.IssueAccessToken(uuid.New())
+
+	keys := m.PublicKeys()
+	if len(keys) != 1 {
+		t.Fatalf("PublicKeys() returned %d keys, want 1", len(keys))
+	}
+	x, err := base64.RawURLEncoding.DecodeString(keys[0].X)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A downstream service only has the published key.
+	parsed, err := jwt.Parse(signed, func(t *jwt.Token) (any, error) {
+		if t.Header["kid"] != keys[0].Kid {
+			return nil, errors.New("unknown kid")
+		}
+		return ed25519.PublicKey(x), nil
+	})
+	if err != nil || !parsed.Valid {
+		t.Errorf("token can't be verified with published key: %v", err)
+	}
+}
+
+func TestManager_NewRefreshToken(t *testing.T) {
+	m := newTestManager(t, time.Minute)
+	t1, h1, _, err := m.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, _, _, _ := m.NewRefreshToken()
+	if t1 == t2 {
+		t.Errorf("NewRefreshToken() returned the same token twice")
+	}
+	if h1 != Hash(t1) || h1 == t1 {
+		t.Errorf("NewRefreshToken() hash = %v, want hash of token", h1)
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	key, err := ParseSigningKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil || !key.Equal(ed25519.NewKeyFromSeed(seed)) {
+		t.Errorf("ParseSigningKey() = %v, %v", key, err)
+	}
+	if _, err := ParseSigningKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Errorf("ParseSigningKey() expected error for short key")
+	}
+}
@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/events"
+	"github.com/sosshik/grpc-user-managment/internal/events/encode"
+)
+
+const (
+	defaultBatchSize = 50
+	defaultTimeout   = 10 * time.Second
+	// maxRetryDelay caps the exponential backoff between attempts.
+	maxRetryDelay = time.Hour
+)
+
+// Dispatcher is an events.Publisher queueing events for delivery to the
+// webhooks subscribed to them.
+type Dispatcher struct {
+	DB domain.DomainInterface
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, events []*domain.UserEvent) error {
+	queued := make([]*domain.WebhookEvent, 0, len(events))
+	for _, ev := range events {
+		payload, err := encode.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("unable to encode user event: %w", err)
+		}
+		queued = append(queued, &domain.WebhookEvent{Offset: ev.Offset, Type: ev.Type, Payload: payload})
+	}
+	return d.DB.EnqueueWebhookDeliveries(ctx, queued)
+}
+
+// Sender attempts due webhook deliveries. A delivery succeeds when the
+// endpoint answers with a 2xx status; failed ones are retried with
+// exponential backoff until MaxAttempts is reached and are then
+// dead-lettered.
+type Sender struct {
+	DB domain.DomainInterface
+	// Client sends the requests. Its Timeout bounds every attempt and is
+	// 10 seconds if unset.
+	Client *http.Client
+	// MaxAttempts is how many times a delivery is attempted.
+	MaxAttempts int
+	// RetryBase is the delay before the first retry. It doubles with every
+	// attempt, up to an hour.
+	RetryBase time.Duration
+	// BatchSize is how many deliveries are claimed at once, 50 if zero.
+	BatchSize int
+}
+
+// Run attempts due deliveries until there are none left. It is meant to be
+// called periodically, e.g. by jobs.Run.
+func (s *Sender) Run(ctx context.Context) error {
+	size := s.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	client := http.Client{Timeout: defaultTimeout}
+	if s.Client != nil {
+		client = *s.Client
+	}
+	if client.Timeout <= 0 {
+		client.Timeout = defaultTimeout
+	}
+	// A batch is attempted concurrently, so it finishes within the client
+	// timeout. Claimed deliveries stay hidden from other senders for twice
+	// as long.
+	lease := 2 * client.Timeout
+
+	for {
+		claimed, err := s.DB.ClaimWebhookDeliveries(ctx, time.Now(), lease, size)
+		if err != nil {
+			return err
+		}
+
+		attempts := make([]domain.DeliveryAttempt, len(claimed))
+		var wg sync.WaitGroup
+		for i, delivery := range claimed {
+			wg.Add(1)
+			go func(i int, delivery *domain.PendingDelivery) {
+				defer wg.Done()
+				attempts[i] = s.attempt(ctx, &client, delivery)
+			}(i, delivery)
+		}
+		wg.Wait()
+
+		for _, attempt := range attempts {
+			if err := s.DB.RecordDeliveryAttempt(ctx, attempt); err != nil {
+				return err
+			}
+		}
+		if len(claimed) < size || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Sender) attempt(ctx context.Context, client *http.Client, delivery *domain.PendingDelivery) domain.DeliveryAttempt {
+	statusCode, err := post(ctx, client, delivery)
+	attempt := domain.DeliveryAttempt{
+		DeliveryID: delivery.ID,
+		At:         time.Now(),
+		StatusCode: statusCode,
+		State:      domain.DeliveryDelivered,
+	}
+	if err == nil {
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+	attempts := delivery.Attempts + 1
+	if attempts >= s.MaxAttempts {
+		attempt.State = domain.DeliveryDeadLetter
+	} else {
+		attempt.State = domain.DeliveryPending
+		attempt.NextAttemptAt = attempt.At.Add(Backoff(s.RetryBase, attempts))
+	}
+	return attempt
+}
+
+func post(ctx context.Context, client *http.Client, delivery *domain.PendingDelivery) (int, error) {
+	ev, err := encode.Unmarshal(delivery.Payload)
+	if err != nil {
+		return 0, err
+	}
+	eventType, err := encode.EventTypeFromProto(ev.GetType())
+	if err != nil {
+		return 0, fmt.Errorf("unable to decode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("unable to build request: %w", err)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, events.Subject(eventType))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the delay before the retry following the given attempt.
+func Backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return min(delay, maxRetryDelay)
+}
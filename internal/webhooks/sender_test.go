@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sosshik/grpc-user-managment/internal/domain"
+	"github.com/sosshik/grpc-user-managment/internal/mocks"
+	proto "github.com/sosshik/grpc-user-managment/protos/gen/go/user_service"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestSender_Run(t *testing.T) {
+	const secret = "0123456789abcdef"
+	payload, err := protojson.Marshal(&proto.UserEvent{Offset: 3, Type: proto.UserEventType_USER_EVENT_TYPE_CREATED})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		attempts  int
+		wantState domain.DeliveryState
+	}{
+		{name: "Delivered", status: http.StatusNoContent, wantState: domain.DeliveryDelivered},
+		{name: "Retried", status: http.StatusInternalServerError, attempts: 1, wantState: domain.DeliveryPending},
+		{name: "Dead letter", status: http.StatusBadGateway, attempts: 2, wantState: domain.DeliveryDeadLetter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verifyErr error
+			var event string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				verifyErr = Verify(secret, r.Header, body, time.Minute, time.Now())
+				event = r.Header.Get(HeaderEvent)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			mockDB := mocks.NewDomainInterface(t)
+			s := &Sender{DB: mockDB, Client: srv.Client(), MaxAttempts: 3, RetryBase: time.Minute}
+
+			delivery := &domain.PendingDelivery{ID: 9, URL: srv.URL, Secret: secret, Attempts: tt.attempts, Payload: payload}
+			mockDB.On("ClaimWebhookDeliveries", mock.Anything, mock.Anything, 2*defaultTimeout, defaultBatchSize).
+				Return([]*domain.PendingDelivery{delivery}, nil).Once()
+			var got domain.DeliveryAttempt
+			mockDB.On("RecordDeliveryAttempt", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				got = args.Get(1).(domain.DeliveryAttempt)
+			}).Return(nil).Once()
+
+			if err := s.Run(context.Background()); err != nil {
+				t.Fatalf("Sender.Run() error = %v", err)
+			}
+			if verifyErr != nil {
+				t.Errorf("receiver rejected the signature: %v", verifyErr)
+			}
+			if event != "users.created" {
+				t.Errorf("%s = %q, want users.created", HeaderEvent, event)
+			}
+			if got.DeliveryID != 9 || got.StatusCode != tt.status || got.State != tt.wantState {
+				t.Errorf("recorded attempt %+v, want status %d and state %v", got, tt.status, tt.wantState)
+			}
+			if tt.wantState == domain.DeliveryPending && got.NextAttemptAt.Sub(got.At) != 2*time.Minute {
+				t.Errorf("next attempt in %v, want %v", got.NextAttemptAt.Sub(got.At), 2*time.Minute)
+			}
+		})
+	}
+}
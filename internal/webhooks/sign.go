@@ -0,0 +1,52 @@
+// Package webhooks delivers user events to the HTTP endpoints subscribed
+// with CreateWebhook.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of webhook requests.
+const (
+	// HeaderID is the id of the delivery. It stays the same across retries.
+	HeaderID = "X-Webhook-Id"
+	// HeaderEvent is the subject of the event, e.g. "users.created".
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the Unix time the request was signed at.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex encoded HMAC-SHA256
+	// of the timestamp, a dot and the body, keyed with the webhook secret.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the HeaderSignature value of a request.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received webhook request. Requests signed
+// more than tolerance away from now are rejected to limit replays.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("malformed timestamp")
+	}
+	timestamp := time.Unix(unix, 0)
+	if now.Sub(timestamp).Abs() > tolerance {
+		return errors.New("timestamp is out of tolerance")
+	}
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
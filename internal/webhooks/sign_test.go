@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"offset":"1"}`)
+	signed := func(secret string, at time.Time) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+		h.Set(HeaderSignature, Sign(secret, at, body))
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr bool
+	}{
+		{name: "Valid", header: signed("secret", now), body: body},
+		{name: "Wrong secret", header: signed("other", now), body: body, wantErr: true},
+		{name: "Tampered body", header: signed("secret", now), body: []byte(`{"offset":"2"}`), wantErr: true},
+		{name: "Too old", header: signed("secret", now.Add(-10*time.Minute)), body: body, wantErr: true},
+		{name: "No headers", header: http.Header{}, body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify("secret", tt.header, tt.body, 5*time.Minute, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 4, want: 4 * time.Minute},
+		{attempt: 20, want: maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := Backoff(30*time.Second, tt.attempt); got != tt.want {
+			t.Errorf("Backoff(30s, %d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
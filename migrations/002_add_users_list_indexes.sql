@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS users_nickname_id_idx ON users (nickname, id);
+CREATE INDEX IF NOT EXISTS users_email_id_idx ON users (email, id);
+CREATE INDEX IF NOT EXISTS users_nickname_pattern_idx ON users (nickname text_pattern_ops);
+CREATE INDEX IF NOT EXISTS users_email_pattern_idx ON users (email text_pattern_ops);
+CREATE INDEX IF NOT EXISTS users_state_idx ON users (state);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS users_state_idx;
+DROP INDEX IF EXISTS users_email_pattern_idx;
+DROP INDEX IF EXISTS users_nickname_pattern_idx;
+DROP INDEX IF EXISTS users_email_id_idx;
+DROP INDEX IF EXISTS users_nickname_id_idx;
+DROP INDEX IF EXISTS users_created_at_id_idx;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- +goose Down
+
+ALTER TABLE users DROP COLUMN IF EXISTS version;
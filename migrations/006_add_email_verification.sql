@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_oid UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS verification_tokens_user_oid_idx ON verification_tokens (user_oid);
+CREATE INDEX IF NOT EXISTS users_state_created_at_idx ON users (state, created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS users_state_created_at_idx;
+DROP TABLE verification_tokens;
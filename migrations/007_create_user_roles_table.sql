@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users ADD CONSTRAINT users_oid_key UNIQUE (oid);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_oid UUID NOT NULL REFERENCES users (oid) ON DELETE CASCADE,
+    role INT NOT NULL,
+    granted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_oid, role)
+);
+
+-- Every existing user gets the user role, new users get it on creation.
+INSERT INTO user_roles (user_oid, role)
+SELECT oid, 1 FROM users
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+
+DROP TABLE user_roles;
+ALTER TABLE users DROP CONSTRAINT users_oid_key;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS state_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMPTZ;
+
+-- +goose Down
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE users DROP COLUMN IF EXISTS state_reason;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    event_type TEXT NOT NULL,
+    user_oid UUID NOT NULL,
+    payload JSONB NOT NULL,
+    -- assigned by the relay in the order events are published
+    event_offset BIGINT UNIQUE,
+    published_at TIMESTAMPTZ
+);
+
+CREATE SEQUENCE IF NOT EXISTS outbox_offset_seq;
+
+CREATE INDEX IF NOT EXISTS outbox_unsequenced_idx ON outbox (id) WHERE event_offset IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (event_offset) WHERE published_at IS NULL;
+
+-- +goose Down
+
+DROP TABLE outbox;
+DROP SEQUENCE IF EXISTS outbox_offset_seq;
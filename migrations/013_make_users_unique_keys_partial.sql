@@ -0,0 +1,17 @@
+-- +goose Up
+-- Deleted users (state -1) keep their email and nickname until they are
+-- purged, without keeping others from registering with them.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_nickname_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_unique_idx ON users (email) WHERE state <> -1;
+CREATE UNIQUE INDEX IF NOT EXISTS users_nickname_unique_idx ON users (nickname) WHERE state <> -1;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS users_nickname_unique_idx;
+DROP INDEX IF EXISTS users_email_unique_idx;
+
+-- Fails while a deleted user shares its email or nickname with another user.
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_nickname_key UNIQUE (nickname);
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Tokens are removed with their user, like its roles. Tokens left behind by
+-- users removed before are dropped first.
+DELETE FROM refresh_tokens WHERE user_oid NOT IN (SELECT oid FROM users);
+DELETE FROM reset_tokens WHERE user_oid NOT IN (SELECT oid FROM users);
+DELETE FROM verification_tokens WHERE user_oid NOT IN (SELECT oid FROM users);
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_user_oid_fkey
+    FOREIGN KEY (user_oid) REFERENCES users (oid) ON DELETE CASCADE;
+ALTER TABLE reset_tokens ADD CONSTRAINT reset_tokens_user_oid_fkey
+    FOREIGN KEY (user_oid) REFERENCES users (oid) ON DELETE CASCADE;
+ALTER TABLE verification_tokens ADD CONSTRAINT verification_tokens_user_oid_fkey
+    FOREIGN KEY (user_oid) REFERENCES users (oid) ON DELETE CASCADE;
+
+-- +goose Down
+
+ALTER TABLE verification_tokens DROP CONSTRAINT IF EXISTS verification_tokens_user_oid_fkey;
+ALTER TABLE reset_tokens DROP CONSTRAINT IF EXISTS reset_tokens_user_oid_fkey;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_oid_fkey;
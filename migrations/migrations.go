@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migrations of the database schema, so
+// that the service can apply them without the files at hand.
+package migrations
+
+import "embed"
+
+// FS holds the goose annotated migrations, applied in order of their
+// numeric prefix.
+//
+//go:embed *.sql
+var FS embed.FS
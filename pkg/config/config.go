@@ -2,40 +2,73 @@ package config
 
 import (
 	"fmt"
-	"sync"
+	"time"
 
 	"github.com/caarlos0/env"
-	log "github.com/sirupsen/logrus"
 )
 
 type Config struct {
-	Port        string `env:"PORT"`
+	Port        string `env:"PORT" envDefault:"8080"`
 	LogLevel    string `env:"LOG_LEVEL" envDefault:"info"`
 	DbUrl       string `env:"DATABASE_URL"`
-	ReconnTime  int    `env:"RECONN_TIME" envDefault:"5"`
 	ConnCheck   bool   `env:"CONN_CHECK" envDefault:"true"`
 	ReconnTries int    `env:"RECONN_TRIES" envDefault:"5"`
-}
 
-var once sync.Once
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"25"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m"`
+	DBConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m"`
+	DBConnectBackoff  time.Duration `env:"DB_CONNECT_BACKOFF" envDefault:"1s"`
+	DBQueryTimeout    time.Duration `env:"DB_QUERY_TIMEOUT" envDefault:"10s"`
+	MigrateOnStart    bool          `env:"MIGRATE_ON_START" envDefault:"false"`
 
-var configInstance *Config
+	TokenIssuer     string        `env:"TOKEN_ISSUER" envDefault:"user_service"`
+	TokenSigningKey string        `env:"TOKEN_SIGNING_KEY"`
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
 
-func GetConfig() *Config {
-	if configInstance == nil {
-		once.Do(func() {
-			fmt.Println("Creating config instance now.")
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	Notifier         string        `env:"NOTIFIER" envDefault:"log"`
+	NotifierFile     string        `env:"NOTIFIER_FILE"`
 
-			var cfg Config
+	EmailVerificationTTL      time.Duration `env:"EMAIL_VERIFICATION_TTL" envDefault:"24h"`
+	UnverifiedRetention       time.Duration `env:"UNVERIFIED_RETENTION" envDefault:"168h"`
+	UnverifiedCleanupInterval time.Duration `env:"UNVERIFIED_CLEANUP_INTERVAL" envDefault:"1h"`
 
-			if err := env.Parse(&cfg); err != nil {
-				log.Fatal(err)
-			}
+	SuspensionCheckInterval time.Duration `env:"SUSPENSION_CHECK_INTERVAL" envDefault:"1m"`
 
-			configInstance = &cfg
-		})
+	DeletedRetention time.Duration `env:"DELETED_RETENTION" envDefault:"720h"`
+	PurgeInterval    time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
 
-	}
+	AdminEmail string `env:"ADMIN_EMAIL"`
+
+	TLSCertFile     string `env:"TLS_CERT_FILE"`
+	TLSKeyFile      string `env:"TLS_KEY_FILE"`
+	TLSClientCAFile string `env:"TLS_CLIENT_CA_FILE"`
+	ServiceRoles    string `env:"SERVICE_ROLES"`
+
+	EventPublisher      string        `env:"EVENT_PUBLISHER" envDefault:"none"`
+	EventPublisherFile  string        `env:"EVENT_PUBLISHER_FILE"`
+	OutboxRelayInterval time.Duration `env:"OUTBOX_RELAY_INTERVAL" envDefault:"1s"`
+	OutboxRetention     time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`
+
+	WebhookSendInterval time.Duration `env:"WEBHOOK_SEND_INTERVAL" envDefault:"5s"`
+	WebhookTimeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookMaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	WebhookRetryBase    time.Duration `env:"WEBHOOK_RETRY_BASE" envDefault:"30s"`
 
-	return configInstance
+	HTTPPort string `env:"HTTP_PORT" envDefault:"8081"`
+
+	GRPCReflection      bool          `env:"GRPC_REFLECTION" envDefault:"false"`
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" envDefault:"5s"`
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+}
+
+// New reads the config from the environment.
+func New() (*Config, error) {
+	var cfg Config
+	if err := env.Parse(&cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
+	return &cfg, nil
 }
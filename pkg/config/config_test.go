@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Port != "8080" || cfg.AccessTokenTTL != 15*time.Minute {
+			t.Errorf("New() port = %q, access token TTL = %s", cfg.Port, cfg.AccessTokenTTL)
+		}
+	})
+
+	t.Run("Fresh instances", func(t *testing.T) {
+		t.Setenv("PORT", "9000")
+		first, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PORT", "9001")
+		second, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Port != "9000" || second.Port != "9001" {
+			t.Errorf("New() ports = %q, %q, want 9000, 9001", first.Port, second.Port)
+		}
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		t.Setenv("ACCESS_TOKEN_TTL", "soon")
+		if _, err := New(); err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+	})
+}